@@ -0,0 +1,33 @@
+package reopen
+
+import (
+	"fmt"
+	"time"
+)
+
+const alonePollInterval = 100 * time.Millisecond
+
+// waitAlone waits until no other process has the current file open when WithRotateWhenAlone is set,
+// or until the writer is closed.
+func (ws *ReopenableWriteSyncer) waitAlone() {
+	if !ws.rotateAlone {
+		return
+	}
+	ticker := time.NewTicker(alonePollInterval)
+	defer ticker.Stop()
+	for {
+		others, err := openedByOthers(ws.getFile().File)
+		if err != nil {
+			ws.handleError(fmt.Errorf("reopen: check open handles: %w", err))
+			return
+		}
+		if !others {
+			return
+		}
+		select {
+		case <-ws.closing:
+			return
+		case <-ticker.C:
+		}
+	}
+}
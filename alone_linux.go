@@ -0,0 +1,43 @@
+package reopen
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// openedByOthers reports whether a process other than this one has f open, by scanning /proc/*/fd.
+// The processes whose descriptors can not be read, e.g. owned by another user, are ignored.
+func openedByOthers(f *os.File) (bool, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	self := strconv.Itoa(os.Getpid())
+	procs, err := os.ReadDir("/proc")
+	if err != nil {
+		return false, err
+	}
+	for _, p := range procs {
+		if p.Name() == self || !isPID(p.Name()) {
+			continue
+		}
+		dir := filepath.Join("/proc", p.Name(), "fd")
+		fds, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			target, err := os.Stat(filepath.Join(dir, fd.Name()))
+			if err == nil && os.SameFile(fi, target) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func isPID(name string) bool {
+	_, err := strconv.Atoi(name)
+	return err == nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package reopen
+
+import "os"
+
+// openedByOthers is only implemented on linux.
+func openedByOthers(*os.File) (bool, error) {
+	return false, nil
+}
@@ -0,0 +1,166 @@
+package reopen
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	archiveAttempts   = 3
+	archiveRetryDelay = time.Second
+)
+
+// ArchiveBackend stores the archived files out of the machine, e.g. in a cloud storage bucket.
+type ArchiveBackend interface {
+	// Upload uploads the archived file at path.
+	Upload(ctx context.Context, path string) error
+}
+
+// ArchiveOption configures an Archiver created by NewArchiver.
+type ArchiveOption func(a *Archiver)
+
+// WithArchiveBackend makes the Archiver upload every archived file to backend.
+func WithArchiveBackend(backend ArchiveBackend) ArchiveOption {
+	return func(a *Archiver) {
+		a.backend = backend
+	}
+}
+
+// WithArchiveRetryDelay specify the delay before the first retry of a failed archive(default is 1s),
+// it doubles after every attempt.
+func WithArchiveRetryDelay(d time.Duration) ArchiveOption {
+	return func(a *Archiver) {
+		a.retryDelay = d
+	}
+}
+
+// Archiver moves the backups created by Rotate to archiveDir/<date of rotation>/<name of the backup>,
+// compressing them with gzip if asked to, see WithArchiver.
+type Archiver struct {
+	dir        string
+	compress   bool
+	backend    ArchiveBackend
+	retryDelay time.Duration
+}
+
+// NewArchiver returns an Archiver moving the backups to archiveDir, compressed with gzip if compress is true.
+func NewArchiver(archiveDir string, compress bool, opts ...ArchiveOption) *Archiver {
+	a := &Archiver{dir: archiveDir, compress: compress, retryDelay: archiveRetryDelay}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Archive moves oldPath, the backup of the rotation ev, to the archive directory and uploads it
+// to the backend if any.
+func (a *Archiver) Archive(oldPath string, ev RotationEvent) error {
+	dir := filepath.Join(a.dir, ev.Time.Format("2006-01-02"))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	dest := filepath.Join(dir, filepath.Base(oldPath))
+	if a.compress {
+		dest += ".gz"
+		if err := gzipFile(oldPath, dest); err != nil {
+			return err
+		}
+		if err := os.Remove(oldPath); err != nil {
+			return err
+		}
+	} else if _, err := os.Stat(oldPath); err == nil {
+		if err := moveFile(oldPath, dest); err != nil {
+			return err
+		}
+	} else if _, destErr := os.Stat(dest); destErr != nil {
+		return err
+	}
+	if a.backend != nil {
+		return a.backend.Upload(context.Background(), dest)
+	}
+	return nil
+}
+
+// gzipFile writes src compressed with gzip to dest, through a temporary file renamed once complete.
+func gzipFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		if os.IsNotExist(err) {
+			if _, destErr := os.Stat(dest); destErr == nil {
+				return nil // archived by a previous attempt, only the upload failed
+			}
+		}
+		return err
+	}
+	defer in.Close()
+	tmp := dest + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	_, err = io.Copy(zw, in)
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dest)
+}
+
+// moveFile renames src to dest, copying it if they are on different filesystems.
+func moveFile(src, dest string) error {
+	if err := os.Rename(src, dest); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// runArchiver archives the backup of ev in the background, retrying with an exponential backoff.
+// It is called once the previous file has been closed, either with reloadMu held or by a close worker.
+func (ws *ReopenableWriteSyncer) runArchiver(ev RotationEvent) {
+	a := ws.archiver
+	if a == nil || ev.BackupPath == "" {
+		return
+	}
+	ws.goBackground("archive", func() {
+		delay := a.retryDelay
+		var err error
+		for attempt := 1; attempt <= archiveAttempts; attempt++ {
+			if err = a.Archive(ev.BackupPath, ev); err == nil {
+				return
+			}
+			if attempt < archiveAttempts {
+				time.Sleep(delay)
+				delay *= 2
+			}
+		}
+		ws.handleError(fmt.Errorf("reopen: archive %s: %w", ev.BackupPath, err))
+	})
+}
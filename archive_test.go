@@ -0,0 +1,57 @@
+package reopen_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/owarai/reopen"
+)
+
+func TestArchiveAfterClose(t *testing.T) {
+	key := bytes.Repeat([]byte{3}, 32)
+	dir := t.TempDir()
+	archiveDir := filepath.Join(dir, "archive")
+	ws, err := reopen.NewWithOptions(filepath.Join(dir, "app.log"), reopen.WithEncryption(key),
+		reopen.WithCloseStrategy(reopen.TimeCloseStrategy(time.Hour)),
+		reopen.WithArchiver(reopen.NewArchiver(archiveDir, true)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the plaintext stays buffered by the encryptor until the previous file is closed
+	writeLines(t, ws, "late line\n")
+	if err := ws.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	if archives, _ := filepath.Glob(filepath.Join(archiveDir, "*", "*")); len(archives) != 0 {
+		t.Fatalf("archived %v before the previous file was closed", archives)
+	}
+	if err := ws.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	archives, err := filepath.Glob(filepath.Join(archiveDir, "*", "*.gz"))
+	if err != nil || len(archives) != 1 {
+		t.Fatalf("archives %v, %v", archives, err)
+	}
+	f, err := os.Open(archives[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(reopen.Decrypt(zr, key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "late line\n" {
+		t.Errorf("archive holds %q", got)
+	}
+}
@@ -0,0 +1,90 @@
+package reopen
+
+import (
+	"sync"
+	"time"
+)
+
+// microBatcher groups the concurrent writes into a single write to the file, see WithMicroBatcher.
+type microBatcher struct {
+	ws       *ReopenableWriteSyncer
+	maxDelay time.Duration
+	maxBytes int
+
+	mu      sync.Mutex
+	cur     *batch
+	flushMu sync.Mutex // held while a batch is written, taken with mu held to keep the batches in order
+}
+
+// batch is a group of writes flushed together.
+type batch struct {
+	buf  []byte
+	done chan struct{}
+	n    int
+	err  error
+}
+
+func newMicroBatcher(ws *ReopenableWriteSyncer, maxDelay time.Duration, maxBytes int) *microBatcher {
+	return &microBatcher{ws: ws, maxDelay: maxDelay, maxBytes: maxBytes}
+}
+
+// write adds p to the current batch and waits until the batch is written.
+func (m *microBatcher) write(p []byte) (n int, err error) {
+	m.mu.Lock()
+	b := m.cur
+	if b == nil {
+		b = &batch{done: make(chan struct{})}
+		m.cur = b
+		m.ws.clock.AfterFunc(m.maxDelay, func() { m.flushBatch(b) })
+	}
+	start := len(b.buf)
+	b.buf = append(b.buf, p...)
+	m.ws.pendingBytes.Add(int64(len(p)))
+	if len(b.buf) >= m.maxBytes {
+		m.flushLocked()
+	} else {
+		m.mu.Unlock()
+	}
+	<-b.done
+	if n = b.n - start; n > len(p) {
+		n = len(p)
+	} else if n < 0 {
+		n = 0
+	}
+	if n == len(p) {
+		return n, nil
+	}
+	return n, b.err
+}
+
+// flushBatch writes b unless it has already been written.
+func (m *microBatcher) flushBatch(b *batch) {
+	m.mu.Lock()
+	if m.cur != b {
+		m.mu.Unlock()
+		return
+	}
+	m.flushLocked()
+}
+
+// flush writes the current batch if any.
+func (m *microBatcher) flush() {
+	m.mu.Lock()
+	if m.cur == nil {
+		m.mu.Unlock()
+		return
+	}
+	m.flushLocked()
+}
+
+// flushLocked writes the current batch, it is called with mu held and releases it.
+func (m *microBatcher) flushLocked() {
+	b := m.cur
+	m.cur = nil
+	m.flushMu.Lock()
+	m.mu.Unlock()
+	b.n, b.err = m.ws.writeFile(b.buf)
+	m.ws.pendingBytes.Add(-int64(len(b.buf)))
+	m.flushMu.Unlock()
+	close(b.done)
+}
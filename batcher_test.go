@@ -0,0 +1,216 @@
+package reopen_test
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/owarai/reopen"
+	"github.com/owarai/reopen/reopentest"
+)
+
+var benchLine = []byte(`{"level":"info","msg":"benchmark line of a typical size","n":42}` + "\n")
+
+// writeSyscalls returns the number of write syscalls made by the process so far, or -1 where /proc/self/io
+// is not available.
+func writeSyscalls() int64 {
+	f, err := os.Open("/proc/self/io")
+	if err != nil {
+		return -1
+	}
+	defer f.Close()
+	for s := bufio.NewScanner(f); s.Scan(); {
+		if v, ok := strings.CutPrefix(s.Text(), "syscw: "); ok {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return -1
+			}
+			return n
+		}
+	}
+	return -1
+}
+
+// benchmarkConcurrentWrites writes b.N lines to ws from goroutines goroutines and reports the write syscalls
+// made per Write.
+func benchmarkConcurrentWrites(b *testing.B, ws *reopen.ReopenableWriteSyncer, goroutines int) {
+	b.SetBytes(int64(len(benchLine)))
+	b.ReportAllocs()
+	before := writeSyscalls()
+	b.ResetTimer()
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		n := b.N / goroutines
+		if g < b.N%goroutines {
+			n++
+		}
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				if _, err := ws.Write(benchLine); err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		}(n)
+	}
+	wg.Wait()
+	b.StopTimer()
+	if after := writeSyscalls(); before >= 0 && after >= 0 {
+		b.ReportMetric(float64(after-before)/float64(b.N), "syscalls/op")
+	}
+}
+
+// BenchmarkMicroBatcher compares the write syscalls of 100 goroutines with and without WithMicroBatcher.
+func BenchmarkMicroBatcher(b *testing.B) {
+	for _, bc := range []struct {
+		name string
+		opts []reopen.Option
+	}{
+		{"direct", nil},
+		{"batched-1us", []reopen.Option{reopen.WithMicroBatcher(time.Microsecond, 64<<10)}},
+		{"batched-100us", []reopen.Option{reopen.WithMicroBatcher(100*time.Microsecond, 64<<10)}},
+		{"batched-1ms", []reopen.Option{reopen.WithMicroBatcher(time.Millisecond, 64<<10)}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			ws, err := reopen.NewWithOptions(filepath.Join(b.TempDir(), "app.log"), bc.opts...)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer reopentest.MustClose(b, ws)
+			benchmarkConcurrentWrites(b, ws, 100)
+		})
+	}
+}
+
+func TestMicroBatcher(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxBytes   int
+		goroutines int
+		lines      int
+	}{
+		{"single writer", 1 << 20, 1, 20},
+		{"concurrent writers", 1 << 20, 8, 50},
+		{"batches split by maxBytes", 64, 8, 50},
+		{"writes larger than maxBytes", 4, 4, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log")
+			ws, err := reopen.NewWithOptions(path, reopen.WithMicroBatcher(time.Millisecond, tt.maxBytes))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var wg sync.WaitGroup
+			for g := 0; g < tt.goroutines; g++ {
+				wg.Add(1)
+				go func(g int) {
+					defer wg.Done()
+					for i := 0; i < tt.lines; i++ {
+						line := strconv.Itoa(g) + "-" + strconv.Itoa(i) + "\n"
+						if n, err := ws.Write([]byte(line)); err != nil || n != len(line) {
+							t.Errorf("Write returned %d, %v", n, err)
+							return
+						}
+					}
+				}(g)
+			}
+			wg.Wait()
+			if err := ws.Close(); err != nil {
+				t.Fatal(err)
+			}
+			b, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// every line is written once, in order for each writer
+			next := make([]int, tt.goroutines)
+			for _, line := range strings.Split(strings.TrimSuffix(string(b), "\n"), "\n") {
+				g, i, _ := strings.Cut(line, "-")
+				gi, _ := strconv.Atoi(g)
+				if ii, _ := strconv.Atoi(i); gi >= tt.goroutines || ii != next[gi] {
+					t.Fatalf("unexpected line %q", line)
+				}
+				next[gi]++
+			}
+			for g, n := range next {
+				if n != tt.lines {
+					t.Errorf("writer %d wrote %d lines, want %d", g, n, tt.lines)
+				}
+			}
+		})
+	}
+}
+
+func TestMicroBatcherWritesBatchTogether(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	clock := reopentest.NewMockClock(time.Now())
+	ws, err := reopen.NewWithOptions(path, reopen.WithClock(clock), reopen.WithMicroBatcher(time.Second, 1<<20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	lines := []string{"a\n", "b\n", "c\n"}
+	var wg sync.WaitGroup
+	for _, line := range lines {
+		wg.Add(1)
+		go func(line string) {
+			defer wg.Done()
+			if _, err := ws.Write([]byte(line)); err != nil {
+				t.Error(err)
+			}
+		}(line)
+	}
+	for deadline := time.Now().Add(5 * time.Second); ws.PendingBytes() != 6; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d bytes pending, want 6", ws.PendingBytes())
+		}
+	}
+	if b, _ := os.ReadFile(path); len(b) != 0 {
+		t.Fatalf("written %q before maxDelay", b)
+	}
+	clock.Advance(time.Second)
+	wg.Wait()
+	if b, err := os.ReadFile(path); err != nil || len(b) != 6 {
+		t.Errorf("file holds %q, %v", b, err)
+	}
+}
+
+func TestMicroBatcherFlushedByMaxBytesAndClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	// the clock never advances, only maxBytes and Close flush the batches
+	ws, err := reopen.NewWithOptions(path, reopen.WithClock(reopentest.NewMockClock(time.Now())),
+		reopen.WithMicroBatcher(time.Second, 8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ws.Write([]byte("12345678")); err != nil {
+		t.Fatal(err)
+	}
+	written := make(chan error, 1)
+	go func() {
+		_, err := ws.Write([]byte("tail\n"))
+		written <- err
+	}()
+	for deadline := time.Now().Add(5 * time.Second); ws.PendingBytes() != 5; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d bytes pending, want 5", ws.PendingBytes())
+		}
+	}
+	if err := ws.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-written; err != nil {
+		t.Errorf("Write flushed by Close returned %v", err)
+	}
+	if b, err := os.ReadFile(path); err != nil || string(b) != "12345678tail\n" {
+		t.Errorf("file holds %q, %v", b, err)
+	}
+}
@@ -0,0 +1,63 @@
+package reopen
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+//go:generate sh -c "printf '// Code generated by go generate; DO NOT EDIT.\\n\\npackage reopen\\n\\nvar version = \"%s\"\\n' \"$(git describe --tags 2>/dev/null)\" > version.go"
+
+const modulePath = "github.com/owarai/reopen"
+
+// commitHash and buildTime can be set at link time like version,
+// e.g. -ldflags "-X github.com/owarai/reopen.commitHash=$(git rev-parse HEAD)".
+var (
+	commitHash string
+	buildTime  string
+)
+
+// BuildInfo describes the build of the package, see Version.
+type BuildInfo struct {
+	Version    string
+	GoVersion  string
+	CommitHash string
+	BuildTime  string
+}
+
+// Version returns the build info of the package. The version is the one set at link time with
+// -ldflags "-X github.com/owarai/reopen.version=v1.2.3", or else the one generated from the git tag,
+// or else the module version recorded in the binary. The commit hash and the build time are only known
+// when set at link time or when the package is built in its own repository.
+func Version() BuildInfo {
+	info := BuildInfo{Version: version, GoVersion: runtime.Version(), CommitHash: commitHash, BuildTime: buildTime}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	var mod *debug.Module
+	if bi.Main.Path == modulePath {
+		mod = &bi.Main
+	}
+	for _, dep := range bi.Deps {
+		if dep.Path == modulePath {
+			mod = dep
+			if dep.Replace != nil {
+				mod = dep.Replace
+			}
+		}
+	}
+	if info.Version == "" && mod != nil {
+		info.Version = mod.Version
+	}
+	if mod == &bi.Main {
+		for _, s := range bi.Settings {
+			switch {
+			case s.Key == "vcs.revision" && info.CommitHash == "":
+				info.CommitHash = s.Value
+			case s.Key == "vcs.time" && info.BuildTime == "":
+				info.BuildTime = s.Value
+			}
+		}
+	}
+	return info
+}
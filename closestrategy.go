@@ -0,0 +1,98 @@
+package reopen
+
+import "time"
+
+type closeKind int
+
+const (
+	closeOnDrain closeKind = iota
+	closeAfterDelay
+	closeImmediately
+)
+
+// CloseStrategy decides when the previous file is closed after a reopen.
+type CloseStrategy struct {
+	kind  closeKind
+	delay time.Duration
+}
+
+// WaitGroupCloseStrategy closes the previous file in the background as soon as the writes in flight on it finish,
+// bounded by WithDrainTimeout.
+func WaitGroupCloseStrategy() CloseStrategy {
+	return CloseStrategy{kind: closeOnDrain}
+}
+
+// TimeCloseStrategy keeps the previous file open for d before closing it in the background,
+// or until Close is called. TimeCloseStrategy(10s) is the default.
+func TimeCloseStrategy(d time.Duration) CloseStrategy {
+	return CloseStrategy{kind: closeAfterDelay, delay: d}
+}
+
+// ImmediateCloseStrategy closes the previous file before the reopen returns, waiting for the writes in flight
+// on it for at most the drain timeout. No write goes to the previous file once the reopen returned.
+func ImmediateCloseStrategy() CloseStrategy {
+	return CloseStrategy{kind: closeImmediately}
+}
+
+// closeOld closes f, which has just been replaced by a reopen, according to the close strategy.
+// It is called with reloadMu held.
+func (ws *ReopenableWriteSyncer) closeOld(f *logFile) {
+	switch ws.closeStrategy.kind {
+	case closeImmediately:
+		f.retire(ws.drainTimeout)
+		_ = ws.closeFile(f)
+	default:
+		c := pendingClose{f: f}
+		if ws.closeStrategy.kind == closeAfterDelay {
+			c.deadline = ws.clock.Now().Add(ws.closeStrategy.delay)
+		}
+		select {
+		case ws.closeQueue <- c:
+		default:
+			// every worker is busy and the queue is full, close synchronously rather than piling up goroutines.
+			ws.closePending(c, false)
+		}
+	}
+}
+
+// pendingClose is a previous file waiting in the close queue.
+type pendingClose struct {
+	f        *logFile
+	deadline time.Time // zero unless closed by TimeCloseStrategy
+}
+
+// closeWorker closes the files of the close queue, see WithCloseWorkers. On Close it closes the files left in the
+// queue without waiting for their deadline.
+func (ws *ReopenableWriteSyncer) closeWorker() {
+	for {
+		select {
+		case c := <-ws.closeQueue:
+			ws.closePending(c, true)
+		case <-ws.closing:
+			for {
+				select {
+				case c := <-ws.closeQueue:
+					ws.closePending(c, false)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// closePending waits for the deadline of c if wait is true, then retires its file and closes it once the writes
+// in flight on it finish, waiting for them for at most the drain timeout.
+func (ws *ReopenableWriteSyncer) closePending(c pendingClose, wait bool) {
+	if d := c.deadline.Sub(ws.clock.Now()); !c.deadline.IsZero() && wait && d > 0 {
+		expired := make(chan struct{})
+		timer := ws.clock.AfterFunc(d, func() { close(expired) })
+		select {
+		case <-expired:
+		case <-ws.closing:
+		}
+		timer.Stop()
+	}
+	c.f.retire(ws.drainTimeout)
+	_ = ws.closeFile(c.f)
+}
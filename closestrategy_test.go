@@ -0,0 +1,151 @@
+package reopen
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// reopenHoldingWrite reopens ws while a write is in flight on its current file, it returns the old file,
+// still acquired, and the channel receiving the result of Reopen once the new file is in use.
+func reopenHoldingWrite(t *testing.T, ws *ReopenableWriteSyncer) (*logFile, chan error) {
+	t.Helper()
+	old := ws.acquire()
+	if old == nil {
+		t.Fatal("no current file")
+	}
+	reopened := make(chan error, 1)
+	go func() { reopened <- ws.Reopen() }()
+	for deadline := time.Now().Add(5 * time.Second); ws.getFile() == old; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("the new file is not in use")
+		}
+	}
+	return old, reopened
+}
+
+func TestImmediateCloseWaitsForInflightWrite(t *testing.T) {
+	ws, err := NewWithOptions(filepath.Join(t.TempDir(), "app.log"), WithCloseStrategy(ImmediateCloseStrategy()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	old, reopened := reopenHoldingWrite(t, ws)
+	select {
+	case err := <-reopened:
+		t.Fatalf("reopen returned with a write in flight: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if _, err := old.Write([]byte("in flight\n")); err != nil {
+		t.Fatalf("old file closed with a write in flight: %v", err)
+	}
+	old.release()
+	if err := <-reopened; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := old.Write([]byte("late\n")); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("old file still open after the write finished: %v", err)
+	}
+}
+
+func TestWaitGroupCloseWaitsForInflightWrite(t *testing.T) {
+	ws, err := NewWithOptions(filepath.Join(t.TempDir(), "app.log"), WithCloseStrategy(WaitGroupCloseStrategy()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	old, reopened := reopenHoldingWrite(t, ws)
+	if err := <-reopened; err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, err := old.Write([]byte("in flight\n")); err != nil {
+		t.Fatalf("old file closed with a write in flight: %v", err)
+	}
+	old.release()
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		if _, err := old.Write(nil); errors.Is(err, os.ErrClosed) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("old file still open after the write finished")
+		}
+	}
+}
+
+func TestDrainTimeoutBoundsReopen(t *testing.T) {
+	const timeout = 50 * time.Millisecond
+	ws, err := NewWithOptions(filepath.Join(t.TempDir(), "app.log"),
+		WithCloseStrategy(ImmediateCloseStrategy()), WithDrainTimeout(timeout))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	start := time.Now()
+	old, reopened := reopenHoldingWrite(t, ws)
+	defer old.release() // the stalled write, never finished before the file is closed
+	select {
+	case err := <-reopened:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("reopen not bounded by the drain timeout")
+	}
+	if elapsed := time.Since(start); elapsed < timeout {
+		t.Errorf("reopen returned after %s, before the drain timeout %s", elapsed, timeout)
+	}
+	if _, err := old.Write([]byte("stalled\n")); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("old file not closed after the drain timeout: %v", err)
+	}
+}
+
+func TestTimeCloseWaitsForInflightWrite(t *testing.T) {
+	tests := []struct {
+		name  string
+		delay time.Duration
+		close bool // Close closes the previous file without waiting for the delay
+	}{
+		{"delay expired", time.Millisecond, false},
+		{"closed before the delay", time.Hour, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ws, err := NewWithOptions(filepath.Join(t.TempDir(), "app.log"),
+				WithCloseStrategy(TimeCloseStrategy(tt.delay)), WithDrainTimeout(5*time.Second))
+			if err != nil {
+				t.Fatal(err)
+			}
+			old, reopened := reopenHoldingWrite(t, ws)
+			if err := <-reopened; err != nil {
+				t.Fatal(err)
+			}
+			closed := make(chan error, 1)
+			if tt.close {
+				go func() { closed <- ws.Close() }()
+			} else {
+				defer ws.Close()
+			}
+			time.Sleep(100 * time.Millisecond)
+			if _, err := old.Write([]byte("in flight\n")); err != nil {
+				t.Fatalf("old file closed with a write in flight: %v", err)
+			}
+			old.release()
+			if tt.close {
+				if err := <-closed; err != nil {
+					t.Fatal(err)
+				}
+			}
+			for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+				if _, err := old.Write(nil); errors.Is(err, os.ErrClosed) {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatal("old file still open after the write finished")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+// Command logtail prints the lines appended to a log file and follows it across rotations like tail -F,
+// see package tail:
+//
+//	logtail --grep error --field level,msg /var/log/app.log
+//
+// The exit code is 1 on error.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/owarai/reopen/tail"
+)
+
+func main() {
+	pattern := flag.String("grep", "", "only print the lines matching this regular expression")
+	fields := flag.String("field", "", "comma separated JSON fields to print instead of the whole lines")
+	fromStart := flag.Bool("from-start", false, "print the file from its beginning instead of its end")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: logtail [flags] file")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	p := &printer{out: os.Stdout}
+	if *pattern != "" {
+		re, err := regexp.Compile(*pattern)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "logtail:", err)
+			os.Exit(1)
+		}
+		p.grep = re
+	}
+	if *fields != "" {
+		p.fields = strings.Split(*fields, ",")
+	}
+	s, err := tail.NewScanner(flag.Arg(0), *fromStart)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "logtail:", err)
+		os.Exit(1)
+	}
+	for s.Scan() {
+		p.print(s.Text())
+	}
+	if err := s.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, "logtail:", err)
+		os.Exit(1)
+	}
+}
+
+// printer filters and formats the lines.
+type printer struct {
+	out    io.Writer
+	grep   *regexp.Regexp
+	fields []string
+}
+
+func (p *printer) print(line string) {
+	if p.grep != nil && !p.grep.MatchString(line) {
+		return
+	}
+	if len(p.fields) > 0 {
+		var m map[string]any
+		if json.Unmarshal([]byte(line), &m) != nil {
+			return // not a JSON object
+		}
+		values := make([]string, len(p.fields))
+		for i, name := range p.fields {
+			if v, ok := m[name]; ok {
+				values[i] = fmt.Sprint(v)
+			}
+		}
+		line = strings.Join(values, "\t")
+	}
+	_, _ = io.WriteString(p.out, line+"\n")
+}
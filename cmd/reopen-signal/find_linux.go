@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// commLen is the length of the names in /proc/<pid>/comm, longer names are truncated.
+const commLen = 15
+
+// findByName returns the pids of the processes named name, except the current one, by scanning /proc/*/comm.
+func findByName(name string) ([]int, error) {
+	if len(name) > commLen {
+		name = name[:commLen]
+	}
+	paths, err := filepath.Glob("/proc/[0-9]*/comm")
+	if err != nil {
+		return nil, err
+	}
+	var pids []int
+	for _, path := range paths {
+		pid, err := strconv.Atoi(filepath.Base(filepath.Dir(path)))
+		if err != nil || pid == os.Getpid() {
+			continue
+		}
+		comm, err := os.ReadFile(path)
+		if err != nil {
+			continue // exited
+		}
+		if strings.TrimSuffix(string(comm), "\n") == name {
+			pids = append(pids, pid)
+		}
+	}
+	return pids, nil
+}
@@ -0,0 +1,34 @@
+//go:build !linux && !windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// findByName returns the pids of the processes named name, except the current one, by running ps.
+func findByName(name string) ([]int, error) {
+	out, err := exec.Command("ps", "-axo", "pid=,comm=").Output()
+	if err != nil {
+		return nil, err
+	}
+	var pids []int
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[0])
+		if err != nil || pid == os.Getpid() {
+			continue
+		}
+		if filepath.Base(strings.Join(fields[1:], " ")) == name {
+			pids = append(pids, pid)
+		}
+	}
+	return pids, nil
+}
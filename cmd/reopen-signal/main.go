@@ -0,0 +1,102 @@
+//go:build !windows
+
+// Command reopen-signal sends a signal to a process found by its pid file or its name,
+// to make the logrotate postrotate scripts trivial to write:
+//
+//	postrotate
+//	    reopen-signal --pidfile /var/run/myapp.pid --signal USR1
+//	endscript
+//
+// The exit code is 0 on success, 1 on error.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+var signals = map[string]syscall.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"QUIT": syscall.SIGQUIT,
+	"TERM": syscall.SIGTERM,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+}
+
+func main() {
+	pidFile := flag.String("pidfile", "", "file containing the pid of the process")
+	name := flag.String("name", "", "name of the process, every process with this name is signaled")
+	sig := flag.String("signal", "USR1", "signal to send, e.g. USR1, SIGHUP or 10")
+	flag.Parse()
+
+	if err := run(*pidFile, *name, *sig); err != nil {
+		fmt.Fprintln(os.Stderr, "reopen-signal:", err)
+		os.Exit(1)
+	}
+}
+
+func run(pidFile, name, sigName string) error {
+	sig, err := parseSignal(sigName)
+	if err != nil {
+		return err
+	}
+	var pids []int
+	switch {
+	case pidFile != "" && name != "":
+		return errors.New("--pidfile and --name are mutually exclusive")
+	case pidFile != "":
+		pid, err := readPidFile(pidFile)
+		if err != nil {
+			return err
+		}
+		pids = []int{pid}
+	case name != "":
+		if pids, err = findByName(name); err != nil {
+			return err
+		}
+		if len(pids) == 0 {
+			return fmt.Errorf("no process named %s", name)
+		}
+	default:
+		return errors.New("--pidfile or --name is required")
+	}
+	for _, pid := range pids {
+		if err := syscall.Kill(pid, sig); err != nil {
+			return fmt.Errorf("signal %d: %w", pid, err)
+		}
+	}
+	return nil
+}
+
+// parseSignal parses a signal name with or without the SIG prefix, or a signal number.
+func parseSignal(s string) (syscall.Signal, error) {
+	if n, err := strconv.Atoi(s); err == nil && n > 0 {
+		return syscall.Signal(n), nil
+	}
+	if sig, ok := signals[strings.TrimPrefix(strings.ToUpper(s), "SIG")]; ok {
+		return sig, nil
+	}
+	return 0, fmt.Errorf("unknown signal %s", s)
+}
+
+// readPidFile returns the pid in path, checking that the process exists.
+func readPidFile(path string) (int, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("%s: invalid pid %q", path, strings.TrimSpace(string(b)))
+	}
+	if err := syscall.Kill(pid, 0); err != nil {
+		return 0, fmt.Errorf("%s: process %d: %w", path, pid, err)
+	}
+	return pid, nil
+}
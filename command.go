@@ -0,0 +1,35 @@
+package reopen
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+const defaultPostRotateCommandTimeout = 60 * time.Second
+
+// postRotateCommand is the command configured by WithPostRotateCommand.
+type postRotateCommand struct {
+	name    string
+	args    []string
+	timeout time.Duration
+}
+
+// runPostRotateCommand runs the post rotate command for the backup of ev in a background goroutine,
+// so the command never runs with reloadMu held. It is called once the previous file has been closed,
+// either with reloadMu held or by a close worker.
+func (ws *ReopenableWriteSyncer) runPostRotateCommand(ev RotationEvent) {
+	c := ws.postRotateCmd
+	if c == nil || c.name == "" || ev.BackupPath == "" {
+		return
+	}
+	ws.goBackground("post-rotate-command", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+		defer cancel()
+		args := append(append([]string(nil), c.args...), ev.BackupPath)
+		if err := exec.CommandContext(ctx, c.name, args...).Run(); err != nil {
+			ws.handleError(fmt.Errorf("reopen: post rotate command %s: %w", c.name, err))
+		}
+	})
+}
@@ -0,0 +1,35 @@
+package reopen_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/owarai/reopen"
+)
+
+func TestPostRotateCommandAfterClose(t *testing.T) {
+	dir := t.TempDir()
+	copied := filepath.Join(dir, "copied")
+	ws, err := reopen.NewWithOptions(filepath.Join(dir, "app.log"),
+		reopen.WithCloseStrategy(reopen.TimeCloseStrategy(time.Hour)),
+		reopen.WithPostRotateCommand("sh", "-c", `cat "$0" > `+copied))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeLines(t, ws, "line\n")
+	if err := ws.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, err := os.Stat(copied); !os.IsNotExist(err) {
+		t.Fatalf("the command ran before the previous file was closed: %v", err)
+	}
+	if err := ws.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := os.ReadFile(copied); err != nil || string(b) != "line\n" {
+		t.Errorf("the command copied %q, %v", b, err)
+	}
+}
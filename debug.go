@@ -0,0 +1,125 @@
+package reopen
+
+import (
+	"fmt"
+	"syscall"
+	"time"
+)
+
+// DebugDump returns a snapshot of the writer's internal state for incident investigations,
+// it can be encoded with encoding/json and is served by HealthHandler with ?debug=true. The map is empty if the writer has no file.
+func (ws *ReopenableWriteSyncer) DebugDump() map[string]any {
+	if ws.noFile() {
+		return map[string]any{}
+	}
+	f := ws.getFile()
+	var inode uint64
+	if fi, err := f.Stat(); err == nil {
+		if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+			inode = uint64(st.Ino)
+		}
+	}
+	signals := make([]string, 0, len(ws.signals))
+	for _, sig := range ws.signals {
+		signals = append(signals, sig.String())
+	}
+	s := ws.Stats()
+	var lastRotation string
+	if !s.LastRotation.IsZero() {
+		lastRotation = s.LastRotation.Format(time.RFC3339Nano)
+	}
+	return map[string]any{
+		"path":             f.Name(),
+		"inode":            inode,
+		"file_mode":        ws.fileMode.String(),
+		"open_flags":       fmt.Sprintf("%#x", ws.openFlag),
+		"state":            ws.State().String(),
+		"rotations":        s.Rotations,
+		"last_rotation":    lastRotation,
+		"writes":           s.Writes,
+		"bytes_written":    s.LogicalBytesWritten,
+		"syncs":            ws.syncs.Load(),
+		"write_errors":     s.WriteErrorCount,
+		"dropped_events":   s.DroppedEvents,
+		"watcher_running":  ws.watcherRunning.Load(),
+		"watcher_restarts": s.WatcherRestartCount,
+		"signals":          signals,
+		"options":          ws.debugOptions(),
+		"build":            Version(),
+		"closing_open":     !ws.closed(),
+	}
+}
+
+// debugOptions describes the options which differ from the defaults.
+func (ws *ReopenableWriteSyncer) debugOptions() []string {
+	var opts []string
+	add := func(enabled bool, format string, args ...any) {
+		if enabled {
+			opts = append(opts, fmt.Sprintf(format, args...))
+		}
+	}
+	add(ws.asyncSync, "WithAsyncSync()")
+	add(ws.syncMinBytes > 0 || ws.syncInterval != asyncSyncInterval, "WithSyncCoalescing(%s, %d)", ws.syncInterval, ws.syncMinBytes)
+	add(ws.drainTimeout != defaultDrainTimeout, "WithDrainTimeout(%s)", ws.drainTimeout)
+	add(ws.truncateOnOpen, "WithTruncateOnOpen()")
+	add(ws.guardInterval > 0, "WithRotationGuardInterval(%s)", ws.guardInterval)
+	if ws.openRetry != nil {
+		add(true, "WithOpenRetry(%d, %s)", ws.openRetry.maxAttempts, ws.openRetry.delay)
+	}
+	add(ws.retryPolicy != nil, "WithOpenRetryPolicy(%T)", ws.retryPolicy)
+	if ws.chmod != nil {
+		add(true, "WithChmod(%v)", *ws.chmod)
+	}
+	add(ws.fileWatcher, "WithFileWatcher()")
+	add(ws.validationInterval > 0, "WithPeriodicValidation(%s)", ws.validationInterval)
+	add(ws.truncationInterval > 0, "WithTruncationDetector(%s)", ws.truncationInterval)
+	add(ws.copyTruncateDetection, "WithCopyTruncateDetection()")
+	add(ws.nfsAware, "WithNFSAware()")
+	add(len(ws.filteredFields) > 0, "WithFieldFilter(%d fields)", len(ws.filteredFields))
+	add(len(ws.newlineFrom) > 0, "WithNewlineNormalization(%q, %q)", ws.newlineFrom, ws.newlineTo)
+	add(ws.maxLineLength > 0, "WithMaxLineLength(%d)", ws.maxLineLength)
+	add(ws.atomicWriteSize > 0, "WithAtomicWriteSize(%d)", ws.atomicWriteSize)
+	add(ws.framer != nil, "WithFramer()")
+	add(ws.jsonValidation, "WithJSONValidation()")
+	add(ws.jsonlValidation, "WithJSONLValidation()")
+	add(ws.jsonInjection, "WithJSONInjection()")
+	add(ws.walPath != "", "WithWALMode(%q, %d)", ws.walPath, ws.walMaxSize)
+	add(ws.maxBackups > 0, "WithMaxBackups(%d)", ws.maxBackups)
+	add(ws.maxTotalSize > 0, "WithMaxTotalSize(%d)", ws.maxTotalSize)
+	add(len(ws.postRotate) > 0, "WithPostRotateHook(%d hooks)", len(ws.postRotate))
+	add(ws.postRotateCmd != nil, "WithPostRotateCommand()")
+	add(ws.notifyAddr != "", "WithUDPNotify(%q)", ws.notifyAddr)
+	add(ws.dedup != nil, "WithDeduplication()")
+	add(ws.activeSuffix != "", "WithActiveSuffix(%q)", ws.activeSuffix)
+	add(ws.rotateAlone, "WithRotateWhenAlone()")
+	add(ws.maxRestarts > 0, "WithMaxRestarts(%d)", ws.maxRestarts)
+	add(ws.maxFailures != defaultMaxConsecutiveErrors, "WithMaxConsecutiveErrors(%d)", ws.maxFailures)
+	add(ws.batcher != nil, "WithMicroBatcher()")
+	add(ws.httpTrigger != nil, "WithHTTPRotationTrigger()")
+	add(ws.archiver != nil, "WithArchiver()")
+	if ws.daily != nil {
+		add(true, "WithDailyRotationAt(%d, %d, %s)", ws.daily.hour, ws.daily.minute, ws.daily.loc)
+	}
+	add(ws.idleTimeout > 0, "WithInactivityClose(%s)", ws.idleTimeout)
+	add(ws.maxJitter > 0, "WithRotationJitter(%s)", ws.maxJitter)
+	add(ws.shards > 1, "WithShardedWrites(%d)", ws.shards)
+	add(ws.cloexec, "WithCloexec()")
+	add(ws.odirect, "WithODirect()")
+	add(ws.directAlignment > 0, "WithDirectIOAlignment(%d)", ws.directAlignment)
+	add(ws.closeWorkers != 1, "WithCloseWorkers(%d)", ws.closeWorkers)
+	add(len(ws.fileHeader) > 0, "WithFileHeader(%q)", ws.fileHeader)
+	add(ws.noAppend, "WithAppendToExisting(false)")
+	add(ws.signalCheck, "WithSignalCheck()")
+	add(ws.atomicReplace, "WithAtomicReplace(%q)", ws.replaceDir)
+	add(ws.clock != RealClock{}, "WithClock(%T)", ws.clock)
+	if o := ws.orderer; o != nil {
+		add(true, "WithOrderedTimestamps(%q, %s)", o.field, o.maxDelay)
+	}
+	add(len(ws.staticPrefix) > 0, "WithStaticPrefix(%q)", ws.staticPrefix)
+	add(ws.bufferPool != nil, "WithBufferPool()")
+	add(ws.encryptionKey != nil, "WithEncryption(<redacted>)")
+	add(ws.lokiLabels != nil, "WithLokiLabels(%s)", ws.lokiLabels)
+	add(ws.sharedKey != "", "WithSharedMemoryCoordination(%q)", ws.sharedKey)
+	add(ws.pathProvider != nil, "WithPathProvider(%T)", ws.pathProvider)
+	return opts
+}
@@ -0,0 +1,137 @@
+package reopen
+
+import (
+	"container/list"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// dedupMaxEntries bounds the number of distinct payloads tracked by WithDeduplication.
+const dedupMaxEntries = 1024
+
+// deduplicator suppresses the payloads repeated more than maxCount times within window, see WithDeduplication.
+type deduplicator struct {
+	window   time.Duration
+	maxCount int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element // of *dedupEntry
+	order   list.List                // oldest first, which is also the order in which the windows expire
+}
+
+type dedupEntry struct {
+	key        string
+	first      time.Time
+	count      int
+	suppressed int
+}
+
+func newDeduplicator(window time.Duration, maxCount int) *deduplicator {
+	return &deduplicator{window: window, maxCount: maxCount, entries: make(map[string]*list.Element)}
+}
+
+// check records p and reports whether it must be suppressed,
+// along with the summaries of the entries which expired or were evicted to make room for p.
+func (d *deduplicator) check(now time.Time, p []byte) (summaries [][]byte, suppress bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	summaries = d.expireLocked(now)
+	if el, ok := d.entries[string(p)]; ok {
+		e := el.Value.(*dedupEntry)
+		if e.count++; e.count > d.maxCount {
+			e.suppressed++
+			return summaries, true
+		}
+		return summaries, false
+	}
+	if d.order.Len() >= dedupMaxEntries {
+		summaries = d.removeLocked(d.order.Front(), summaries)
+	}
+	e := &dedupEntry{key: string(p), first: now, count: 1}
+	d.entries[e.key] = d.order.PushBack(e)
+	return summaries, false
+}
+
+// expire removes the entries whose window ended at now, or all of them if now is zero,
+// and returns their summaries in the order of the entries.
+func (d *deduplicator) expire(now time.Time) [][]byte {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.expireLocked(now)
+}
+
+func (d *deduplicator) expireLocked(now time.Time) (summaries [][]byte) {
+	for el := d.order.Front(); el != nil; el = d.order.Front() {
+		if !now.IsZero() && now.Sub(el.Value.(*dedupEntry).first) < d.window {
+			break
+		}
+		summaries = d.removeLocked(el, summaries)
+	}
+	return summaries
+}
+
+// removeLocked removes the entry el and appends its summary to summaries if it suppressed some payloads.
+func (d *deduplicator) removeLocked(el *list.Element, summaries [][]byte) [][]byte {
+	e := d.order.Remove(el).(*dedupEntry)
+	delete(d.entries, e.key)
+	if e.suppressed > 0 {
+		summaries = append(summaries, repeatedSummary(e.suppressed))
+	}
+	return summaries
+}
+
+// nextExpiry returns when the window of the oldest entry ends, or now + window if there is none.
+func (d *deduplicator) nextExpiry(now time.Time) time.Time {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if el := d.order.Front(); el != nil {
+		return el.Value.(*dedupEntry).first.Add(d.window)
+	}
+	return now.Add(d.window)
+}
+
+// flush removes every entry and returns the pending summaries.
+func (d *deduplicator) flush() [][]byte {
+	return d.expire(time.Time{})
+}
+
+func repeatedSummary(n int) []byte {
+	return []byte("previous message repeated " + strconv.Itoa(n) + " times\n")
+}
+
+// writeDeduplicated writes p unless the deduplicator suppresses it, a suppressed payload is reported as written.
+func (ws *ReopenableWriteSyncer) writeDeduplicated(p []byte) (n int, err error) {
+	summaries, suppress := ws.dedup.check(ws.clock.Now(), p)
+	for _, s := range summaries {
+		if _, err := ws.write(s); err != nil {
+			return 0, err
+		}
+	}
+	if suppress {
+		return len(p), nil
+	}
+	return ws.write(p)
+}
+
+// flushDeduplicated writes the summaries of WithDeduplication as soon as the window of their payload ends,
+// instead of waiting for the next write.
+func (ws *ReopenableWriteSyncer) flushDeduplicated() {
+	for {
+		now := ws.clock.Now()
+		due := make(chan struct{})
+		timer := ws.clock.AfterFunc(ws.dedup.nextExpiry(now).Sub(now), func() { close(due) })
+		select {
+		case <-ws.closing:
+			timer.Stop()
+			return
+		case <-due:
+		}
+		for _, s := range ws.dedup.expire(ws.clock.Now()) {
+			if _, err := ws.write(s); err != nil && err != os.ErrClosed {
+				ws.handleError(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package reopen_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/owarai/reopen"
+	"github.com/owarai/reopen/reopentest"
+)
+
+func TestDeduplicationSummariesOnTimer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	clock := reopentest.NewMockClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	ws, err := reopen.NewWithOptions(path, reopen.WithClock(clock), reopen.WithDeduplication(time.Second, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopentest.MustClose(t, ws)
+	for _, line := range []string{"a", "b", "a", "a", "b", "b", "a", "b", "b"} {
+		if _, err := ws.Write([]byte(line + "\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := "a\nb\na\nb\n" +
+		"previous message repeated 2 times\n" + // a
+		"previous message repeated 3 times\n" // b
+	var got []byte
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		clock.Advance(time.Second) // again in case the flushing goroutine armed its timer after the previous one
+		if got, err = os.ReadFile(path); err != nil {
+			t.Fatal(err)
+		}
+		if strings.Count(string(got), "\n") >= strings.Count(want, "\n") {
+			break
+		}
+	}
+	if string(got) != want {
+		t.Errorf("file content %q, want %q without another write", got, want)
+	}
+}
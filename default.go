@@ -0,0 +1,71 @@
+package reopen
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	defaultMu sync.Mutex
+	defaultWS atomic.Pointer[ReopenableWriteSyncer]
+)
+
+// SetDefault create the package level writeSyncer used by Write and Sync.
+// A previously set default writeSyncer is closed.
+func SetDefault(file string, opts ...Option) error {
+	ws, err := NewWithOptions(file, opts...)
+	if err != nil {
+		return err
+	}
+	defaultMu.Lock()
+	old := defaultWS.Swap(ws)
+	defaultMu.Unlock()
+	if old != nil {
+		_ = old.Close()
+	}
+	return nil
+}
+
+// Default returns the package level writeSyncer.
+// If SetDefault was never called, it is created on first use for os.Args[0]+".log" with mode 0644,
+// Default returns nil if that fails.
+func Default() *ReopenableWriteSyncer {
+	ws, _ := loadDefault()
+	return ws
+}
+
+// Write writes p to the default writeSyncer.
+func Write(p []byte) (int, error) {
+	ws, err := loadDefault()
+	if err != nil {
+		return 0, err
+	}
+	return ws.Write(p)
+}
+
+// Sync syncs the default writeSyncer.
+func Sync() error {
+	ws, err := loadDefault()
+	if err != nil {
+		return err
+	}
+	return ws.Sync()
+}
+
+func loadDefault() (*ReopenableWriteSyncer, error) {
+	if ws := defaultWS.Load(); ws != nil {
+		return ws, nil
+	}
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	if ws := defaultWS.Load(); ws != nil {
+		return ws, nil
+	}
+	ws, err := NewWithOptions(os.Args[0]+".log", WithFileMode(0644))
+	if err != nil {
+		return nil, err
+	}
+	defaultWS.Store(ws)
+	return ws, nil
+}
@@ -0,0 +1,100 @@
+package reopen
+
+import (
+	"io"
+	"os"
+	"unsafe"
+)
+
+// alignedBuffer returns a zeroed buffer of n bytes whose address is a multiple of directAlignment,
+// reusing the buffer of the previous write. It is called with alignMu held.
+func (ws *ReopenableWriteSyncer) alignedBuffer(n int) []byte {
+	if cap(ws.alignBuf) < n {
+		raw := make([]byte, n+ws.directAlignment)
+		off := 0
+		if rem := int(uintptr(unsafe.Pointer(&raw[0])) % uintptr(ws.directAlignment)); rem != 0 {
+			off = ws.directAlignment - rem
+		}
+		ws.alignBuf = raw[off : off+n]
+	}
+	buf := ws.alignBuf[:n]
+	for i := range buf {
+		buf[i] = 0
+	}
+	return buf
+}
+
+// writeAligned writes p to f with writes whose offset, length and buffer are aligned to directAlignment,
+// as required by O_DIRECT. The last partial block is padded with zeros and written again by the next write,
+// the padding is truncated when f is closed.
+func (ws *ReopenableWriteSyncer) writeAligned(f *logFile, p []byte) (int, error) {
+	bs := int64(ws.directAlignment)
+	ws.alignMu.Lock()
+	defer ws.alignMu.Unlock()
+	for f.successor != nil {
+		f = f.successor
+	}
+	if f.tail == nil && f.logical%bs != 0 {
+		tail, err := readTail(f.Name(), f.logical-f.logical%bs, f.logical%bs)
+		if err != nil {
+			return 0, err
+		}
+		f.tail = tail
+	}
+	start := f.logical - f.logical%bs
+	size := len(f.tail) + len(p)
+	buf := ws.alignedBuffer((size + ws.directAlignment - 1) / ws.directAlignment * ws.directAlignment)
+	copy(buf, f.tail)
+	copy(buf[len(f.tail):], p)
+	if _, err := f.WriteAt(buf, start); err != nil {
+		return 0, err
+	}
+	f.logical += int64(len(p))
+	rem := int(f.logical % bs)
+	f.tail = append(f.tail[:0], buf[size-rem:size]...)
+	return len(p), nil
+}
+
+// readTail reads the last partial block of the file at path, which O_DIRECT can not read unaligned.
+func readTail(path string, off, n int64) ([]byte, error) {
+	r, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	tail := make([]byte, n)
+	if _, err := r.ReadAt(tail, off); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return tail, nil
+}
+
+// inheritAligned makes next, reopened after prev, continue the aligned writes of prev if they are the same file,
+// whose size includes the padding of prev.
+func (ws *ReopenableWriteSyncer) inheritAligned(prev, next *logFile) {
+	ws.alignMu.Lock()
+	defer ws.alignMu.Unlock()
+	prevInfo, err := prev.Stat()
+	if err != nil {
+		return
+	}
+	nextInfo, err := next.Stat()
+	if err != nil || !os.SameFile(prevInfo, nextInfo) {
+		return
+	}
+	next.logical = prev.logical
+	next.tail = append([]byte(nil), prev.tail...)
+	prev.successor = next
+}
+
+// truncatePadding truncates the padding written after the last aligned write to f.
+func (ws *ReopenableWriteSyncer) truncatePadding(f *logFile) {
+	ws.alignMu.Lock()
+	defer ws.alignMu.Unlock()
+	if f.successor != nil {
+		return
+	}
+	if err := f.Truncate(f.logical); err != nil {
+		ws.handleError(err)
+	}
+}
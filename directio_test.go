@@ -0,0 +1,68 @@
+package reopen_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/owarai/reopen"
+)
+
+func TestDirectIOAlignment(t *testing.T) {
+	const blockSize = 512
+	long := strings.Repeat("x", 700) + "\n"
+	tests := []struct {
+		name     string
+		existing string
+		write    func(t *testing.T, ws *reopen.ReopenableWriteSyncer)
+		want     string
+	}{
+		{"partial block", "", func(t *testing.T, ws *reopen.ReopenableWriteSyncer) {
+			writeLines(t, ws, "one\n", "two\n")
+		}, "one\ntwo\n"},
+		{"several blocks", "", func(t *testing.T, ws *reopen.ReopenableWriteSyncer) {
+			writeLines(t, ws, long, long, "tail\n")
+		}, long + long + "tail\n"},
+		{"appending to an unaligned file", "existing\n", func(t *testing.T, ws *reopen.ReopenableWriteSyncer) {
+			writeLines(t, ws, "appended\n")
+		}, "existing\nappended\n"},
+		{"across a reopen of the same file", "", func(t *testing.T, ws *reopen.ReopenableWriteSyncer) {
+			writeLines(t, ws, "before\n")
+			if err := ws.Reopen(); err != nil {
+				t.Fatal(err)
+			}
+			writeLines(t, ws, "after\n")
+		}, "before\nafter\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log")
+			if tt.existing != "" {
+				if err := os.WriteFile(path, []byte(tt.existing), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			ws, err := reopen.NewWithOptions(path, reopen.WithDirectIOAlignment(blockSize))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.write(t, ws)
+			// the last block is padded while the file is open
+			b, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(b)%blockSize != 0 || !strings.HasPrefix(string(b), tt.want) ||
+				strings.Trim(string(b[len(tt.want):]), "\x00") != "" {
+				t.Errorf("open file of %d bytes holds %.40q", len(b), b)
+			}
+			if err := ws.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if b, err := os.ReadFile(path); err != nil || string(b) != tt.want {
+				t.Errorf("closed file holds %.40q, %v, want %.40q", b, err, tt.want)
+			}
+		})
+	}
+}
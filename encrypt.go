@@ -0,0 +1,243 @@
+package reopen
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+const (
+	// encryptionBlockSize is the size of the plaintext of a full record.
+	encryptionBlockSize = 64 << 10
+	// encryptionNonceSize is the size of the random nonce starting every encrypted file.
+	encryptionNonceSize = 12
+	// encryptionRecordHeaderLen is the size of the big-endian plaintext length prefixing every record.
+	encryptionRecordHeaderLen = 4
+	encryptionTagSize         = 16
+)
+
+// ErrDecryption is returned by the reader of Decrypt when a record is not authentic, e.g. a wrong key.
+var ErrDecryption = errors.New("reopen: decryption failed")
+
+// newAEAD returns the AES-256-GCM cipher for key.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("reopen: encryption key of %d bytes, want 32", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptor encrypts the writes of a file, see WithEncryption. An encrypted file is the random nonce followed by
+// records: the plaintext length, then its ciphertext and tag. The nonce of record i is the file nonce whose last
+// 8 bytes are XORed with i, the authenticated data is i and the plaintext length.
+type encryptor struct {
+	aead  cipher.AEAD
+	nonce [encryptionNonceSize]byte
+
+	mu  sync.Mutex
+	seq uint64
+	buf []byte // plaintext of the record being filled
+	err error  // the file is unusable once a record failed to be written
+}
+
+// newEncryptor returns the encryptor of f, it writes the nonce of an empty file or reads the nonce and counts
+// the records of a file being appended to.
+func (ws *ReopenableWriteSyncer) newEncryptor(f *logFile) *encryptor {
+	e := &encryptor{aead: ws.aead}
+	if f.baseSize == 0 {
+		if _, e.err = rand.Read(e.nonce[:]); e.err == nil {
+			var n int
+			n, e.err = f.Write(e.nonce[:])
+			f.written.Add(int64(n))
+			ws.physicalBytes.Add(int64(n))
+		}
+	} else {
+		e.seq, e.err = readEncryptedFile(f.Name(), e.nonce[:])
+	}
+	if e.err != nil {
+		e.err = fmt.Errorf("reopen: encryption of %s: %w", f.Name(), e.err)
+		ws.handleError(e.err)
+	}
+	return e
+}
+
+// sharedEncryptor returns the encryptor of the current file if fi is a new descriptor of the same non-empty file,
+// e.g. after a reopen which did not rotate it. Both descriptors then write through it until the previous one is
+// closed, so their records are numbered in order and no record number, hence no nonce, is used twice.
+func (ws *ReopenableWriteSyncer) sharedEncryptor(fi os.FileInfo) *encryptor {
+	old := ws.getFile()
+	if old == nil || old.enc == nil || fi.Size() == 0 {
+		return nil
+	}
+	if cur, err := old.Stat(); err != nil || !os.SameFile(fi, cur) {
+		return nil
+	}
+	return old.enc
+}
+
+// readEncryptedFile reads the nonce of the file at path into nonce and returns the number of records.
+func readEncryptedFile(path string, nonce []byte) (uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	if _, err := io.ReadFull(r, nonce); err != nil {
+		return 0, err
+	}
+	var header [encryptionRecordHeaderLen]byte
+	var seq uint64
+	for {
+		if _, err := io.ReadFull(r, header[:]); err == io.EOF {
+			return seq, nil
+		} else if err != nil {
+			return 0, err
+		}
+		size := int64(binary.BigEndian.Uint32(header[:])) + encryptionTagSize
+		if n, err := r.Discard(int(size)); int64(n) != size {
+			return 0, fmt.Errorf("truncated record %d: %w", seq, err)
+		}
+		seq++
+	}
+}
+
+// write buffers p and writes the full records to w,
+// it returns the number of bytes of p accepted and the number of bytes written to w.
+func (e *encryptor) write(w io.Writer, p []byte) (n, written int, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.err != nil {
+		return 0, 0, e.err
+	}
+	e.buf = append(e.buf, p...)
+	for len(e.buf) >= encryptionBlockSize && err == nil {
+		var k int
+		k, err = e.seal(w, encryptionBlockSize)
+		written += k
+	}
+	return len(p), written, err
+}
+
+// flush writes the buffered plaintext as a record, even if it is not full.
+func (e *encryptor) flush(w io.Writer) (int, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.err != nil || len(e.buf) == 0 {
+		return 0, e.err
+	}
+	return e.seal(w, len(e.buf))
+}
+
+// seal writes the record of the first size bytes of buf, it is called with mu held.
+func (e *encryptor) seal(w io.Writer, size int) (int, error) {
+	var nonce [encryptionNonceSize]byte
+	var aad [8 + encryptionRecordHeaderLen]byte
+	recordNonce(nonce[:], e.nonce[:], e.seq)
+	binary.BigEndian.PutUint64(aad[:], e.seq)
+	binary.BigEndian.PutUint32(aad[8:], uint32(size))
+
+	record := make([]byte, encryptionRecordHeaderLen, encryptionRecordHeaderLen+size+encryptionTagSize)
+	copy(record, aad[8:])
+	record = e.aead.Seal(record, nonce[:], e.buf[:size], aad[:])
+	n, err := w.Write(record)
+	if err != nil {
+		e.err = err
+		return n, err
+	}
+	e.seq++
+	e.buf = append(e.buf[:0], e.buf[size:]...)
+	return n, nil
+}
+
+// recordNonce sets nonce to the nonce of record seq of a file whose nonce is base.
+func recordNonce(nonce, base []byte, seq uint64) {
+	copy(nonce, base)
+	var s [8]byte
+	binary.BigEndian.PutUint64(s[:], seq)
+	for i := range s {
+		nonce[encryptionNonceSize-8+i] ^= s[i]
+	}
+}
+
+// flushEncrypted writes the buffered plaintext of f as a record.
+func (ws *ReopenableWriteSyncer) flushEncrypted(f *logFile) error {
+	n, err := f.enc.flush(f.File)
+	f.written.Add(int64(n))
+	ws.physicalBytes.Add(int64(n))
+	return err
+}
+
+// Decrypt returns a reader of the plaintext of the file encrypted with key by WithEncryption read from r.
+// The reader returns ErrDecryption on a record which is not authentic and io.ErrUnexpectedEOF on a truncated one.
+func Decrypt(r io.Reader, key []byte) io.Reader {
+	aead, err := newAEAD(key)
+	return &decryptReader{r: bufio.NewReader(r), aead: aead, err: err}
+}
+
+type decryptReader struct {
+	r     *bufio.Reader
+	aead  cipher.AEAD
+	nonce []byte // nil until read
+	seq   uint64
+	buf   []byte // plaintext of the current record not yet read
+	err   error
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		d.err = d.next()
+	}
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+// next decrypts the next record into buf.
+func (d *decryptReader) next() error {
+	if d.nonce == nil {
+		d.nonce = make([]byte, encryptionNonceSize)
+		if _, err := io.ReadFull(d.r, d.nonce); err != nil {
+			return err
+		}
+	}
+	var aad [8 + encryptionRecordHeaderLen]byte
+	if _, err := io.ReadFull(d.r, aad[8:]); err != nil {
+		return err
+	}
+	size := int(binary.BigEndian.Uint32(aad[8:]))
+	if size > encryptionBlockSize {
+		return ErrDecryption
+	}
+	record := make([]byte, size+encryptionTagSize)
+	if _, err := io.ReadFull(d.r, record); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	var nonce [encryptionNonceSize]byte
+	recordNonce(nonce[:], d.nonce, d.seq)
+	binary.BigEndian.PutUint64(aad[:], d.seq)
+	plain, err := d.aead.Open(record[:0], nonce[:], record, aad[:])
+	if err != nil {
+		return ErrDecryption
+	}
+	d.seq++
+	d.buf = plain
+	return nil
+}
@@ -0,0 +1,114 @@
+package reopen_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/owarai/reopen"
+)
+
+func TestEncryptionRoundTrip(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	large := strings.Repeat("0123456789abcdef", 5<<10) + "\n" // spans a full record and a partial one
+	tests := []struct {
+		name string
+		run  func(t *testing.T, ws *reopen.ReopenableWriteSyncer, path string) string
+	}{
+		{"write and close", func(t *testing.T, ws *reopen.ReopenableWriteSyncer, _ string) string {
+			writeLines(t, ws, "one\n", "two\n")
+			return "one\ntwo\n"
+		}},
+		{"records larger than a block", func(t *testing.T, ws *reopen.ReopenableWriteSyncer, _ string) string {
+			writeLines(t, ws, large, "tail\n")
+			return large + "tail\n"
+		}},
+		{"sync between writes", func(t *testing.T, ws *reopen.ReopenableWriteSyncer, _ string) string {
+			writeLines(t, ws, "one\n")
+			if err := ws.Sync(); err != nil {
+				t.Fatal(err)
+			}
+			writeLines(t, ws, "two\n")
+			return "one\ntwo\n"
+		}},
+		{"across a reopen", func(t *testing.T, ws *reopen.ReopenableWriteSyncer, _ string) string {
+			writeLines(t, ws, "before\n")
+			if err := ws.Reopen(); err != nil {
+				t.Fatal(err)
+			}
+			writeLines(t, ws, "after\n")
+			return "before\nafter\n"
+		}},
+		{"appending after a partial record", func(t *testing.T, ws *reopen.ReopenableWriteSyncer, path string) string {
+			writeLines(t, ws, "first writer\n")
+			if err := ws.Close(); err != nil {
+				t.Fatal(err)
+			}
+			again, err := reopen.NewWithOptions(path, reopen.WithEncryption(key))
+			if err != nil {
+				t.Fatal(err)
+			}
+			writeLines(t, again, "second writer\n")
+			if err := again.Close(); err != nil {
+				t.Fatal(err)
+			}
+			return "first writer\nsecond writer\n"
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log")
+			ws, err := reopen.NewWithOptions(path, reopen.WithEncryption(key))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := tt.run(t, ws, path)
+			_ = ws.Close()
+
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Contains(raw, []byte(want[:4])) {
+				t.Errorf("the file holds plaintext")
+			}
+			got, err := io.ReadAll(reopen.Decrypt(bytes.NewReader(raw), key))
+			if err != nil {
+				t.Fatalf("Decrypt: %v", err)
+			}
+			if string(got) != want {
+				t.Errorf("decrypted %d bytes %.40q, want %d bytes %.40q", len(got), got, len(want), want)
+			}
+		})
+	}
+}
+
+func TestDecryptWrongKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	ws, err := reopen.NewWithOptions(path, reopen.WithEncryption(bytes.Repeat([]byte{1}, 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeLines(t, ws, "secret\n")
+	_ = ws.Close()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := io.ReadAll(reopen.Decrypt(f, bytes.Repeat([]byte{2}, 32))); err != reopen.ErrDecryption {
+		t.Errorf("Decrypt with the wrong key returned %v, want ErrDecryption", err)
+	}
+}
+
+func writeLines(t *testing.T, ws *reopen.ReopenableWriteSyncer, lines ...string) {
+	t.Helper()
+	for _, line := range lines {
+		if _, err := ws.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package reopen
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNetworkFilesystem is reported to the error handler by WithNFSAware when the log file lives on a network
+// filesystem, whose inode numbers are not stable, and WithFileWatcher or WithPeriodicValidation was requested:
+// they are not started, rotation then relies on signals only.
+var ErrNetworkFilesystem = errors.New("reopen: log file is on a network filesystem, rotation relies on signals only")
+
+// ErrWatcherDead is reported to the error handler when the signal watcher gives up after its last restart,
+// signals no longer reopen the file. See WithMaxRestarts.
+var ErrWatcherDead = errors.New("reopen: signal watcher is dead")
+
+// ErrSignalBlocked is returned by the constructors with WithSignalCheck when a reopen signal sent by the process
+// to itself is not delivered, e.g. because it is blocked by C code, rotation would then silently never happen.
+var ErrSignalBlocked = errors.New("reopen: signal is not delivered")
+
+// ErrNotApplicable is returned by the constructors when an option does not apply to the writer,
+// e.g. WithLevelSizeMap on a writer which does not split its files by level.
+var ErrNotApplicable = errors.New("reopen: option not applicable to this writer")
+
+// handleError reports err to the handler configured by WithErrorHandler.
+func (ws *ReopenableWriteSyncer) handleError(err error) {
+	if err != nil && ws.errorHandler != nil {
+		ws.errorHandler(err)
+	}
+}
+
+// RetryEvent is reported to the error handler before each retry of a failed open, see WithOpenRetry.
+// It is not a failure of the writer, handlers can tell it apart with errors.As.
+type RetryEvent struct {
+	// Attempt is the number of the attempt which failed, starting at 1.
+	Attempt int
+	// Delay is the time waited before the next attempt.
+	Delay time.Duration
+	// Err is the error of the failed attempt.
+	Err error
+}
+
+func (e *RetryEvent) Error() string {
+	return fmt.Sprintf("reopen: open attempt %d failed, retrying in %s: %v", e.Attempt, e.Delay, e.Err)
+}
+
+func (e *RetryEvent) Unwrap() error {
+	return e.Err
+}
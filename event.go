@@ -0,0 +1,54 @@
+package reopen
+
+import (
+	"os"
+	"time"
+)
+
+// RotationTrigger tells what caused a reopen.
+type RotationTrigger string
+
+const (
+	// TriggerSignal is a reopen caused by one of the monitored signals.
+	TriggerSignal RotationTrigger = "signal"
+	// TriggerManual is a reopen caused by calling Reopen.
+	TriggerManual RotationTrigger = "manual"
+	// TriggerRelocate is a reopen caused by calling Relocate.
+	TriggerRelocate RotationTrigger = "relocate"
+	// TriggerRotate is a reopen caused by calling Rotate.
+	TriggerRotate RotationTrigger = "rotate"
+	// TriggerTruncation is a reopen caused by the truncation detector, see WithTruncationDetector.
+	TriggerTruncation RotationTrigger = "truncation"
+	// TriggerValidation is a reopen caused by the periodic validation, see WithPeriodicValidation.
+	TriggerValidation RotationTrigger = "validation"
+	// TriggerWatcher is a reopen caused by the file watcher, see WithFileWatcher.
+	TriggerWatcher RotationTrigger = "watcher"
+	// TriggerCoordination is a reopen following the rotation of another process, see WithSharedMemoryCoordination.
+	TriggerCoordination RotationTrigger = "coordination"
+)
+
+// RotationEvent describes a successful reopen of the log file.
+type RotationEvent struct {
+	// Path is the path of the newly opened file.
+	Path string
+	// BackupPath is where the previous file has been renamed to, it is only known for TriggerRotate.
+	BackupPath string
+	// Time is when the new file was opened.
+	Time time.Time
+	// Trigger is what caused the reopen.
+	Trigger RotationTrigger
+	// Signal is the signal which triggered the reopen, it is nil unless Trigger is TriggerSignal.
+	Signal os.Signal
+}
+
+// emit sends ev to the event stream without blocking, ev is dropped if the consumer is slow.
+func (ws *ReopenableWriteSyncer) emit(ev RotationEvent) {
+	if ws.events == nil {
+		return
+	}
+	select {
+	case ws.events <- ev:
+	default:
+		ws.droppedEvents.Add(1)
+	}
+}
@@ -1,6 +1,6 @@
 module github.com/owarai/reopen/example
 
-go 1.15
+go 1.21
 
 replace github.com/owarai/reopen => ../
 
@@ -8,3 +8,8 @@ require (
 	github.com/owarai/reopen v0.0.0
 	go.uber.org/zap v1.21.0
 )
+
+require (
+	go.uber.org/atomic v1.7.0 // indirect
+	go.uber.org/multierr v1.6.0 // indirect
+)
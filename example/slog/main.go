@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/owarai/reopen"
+)
+
+// Note:
+// use logrotate to test:
+//
+// add config file to $HOME/.config/logrotate.conf
+// content like this: {{xxx}} aims to be replaced to true value.
+//
+//	{{your_logfile_dir}}/example-slog.log {{your_logfile_dir}}/example-slog.log.wf {
+//	   hourly
+//	   rotate 8
+//	   size 1M
+//	   missingok
+//	   notifempty
+//	   compress
+//	   sharedscripts
+//	   postrotate
+//	   /bin/killall -USR1 {{your_process_name}}
+//	   endscript
+//	}
+//
+// run this program from the example module, log/slog needs go 1.21 while the root module is go 1.20,
+// so `go run ./example/slog` does not work from the repository root:
+//
+//	cd example && go run ./slog
+//
+// and run logrotate periodically.
+//
+//	logrotate $HOME/.config/logrotate.conf --state $HOME/.config/logrotate-state --verbose
+func main() {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+		defer cancel()
+
+		select {
+		case <-time.After(5 * time.Minute):
+			return
+		case sig := <-signals:
+			fmt.Printf("terminating via signal: %s", sig)
+			return
+		}
+	}()
+
+	// Both files are reopened together when the process receives USR1.
+	group := reopen.NewRotationGroup(syscall.SIGUSR1)
+	defer group.Close()
+
+	logger, err := newLogger(group, "example-slog")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	slogLog(ctx, logger, time.Millisecond)
+}
+
+func slogLog(ctx context.Context, logger *slog.Logger, writeInterval time.Duration) {
+	url := "www.google.com"
+	for {
+		time.Sleep(writeInterval)
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		logger.Info("Operation execution successful", slog.String("url", url),
+			slog.Int("attempt", 1), slog.Duration("backoff", 1))
+		if rand.Intn(10) >= 5 {
+			logger.Error("Failed to fetch URL",
+				slog.String("url", url), slog.Int("attempt", 3), slog.Duration("backoff", 1))
+		}
+	}
+}
+
+func newLogger(group *reopen.RotationGroup, destName string) (*slog.Logger, error) {
+	logInfos, err := group.New(destName+".log", reopen.WithFileMode(0644))
+	if err != nil {
+		return nil, err
+	}
+	logErrors, err := group.New(destName+".log.wf", reopen.WithFileMode(0644))
+	if err != nil {
+		return nil, err
+	}
+
+	return slog.New(&teeHandler{
+		slog.NewJSONHandler(logInfos, &slog.HandlerOptions{Level: slog.LevelInfo}),
+		slog.NewJSONHandler(logErrors, &slog.HandlerOptions{Level: slog.LevelError}),
+	}), nil
+}
+
+// teeHandler dispatches each record to every handler which is enabled for its level.
+type teeHandler []slog.Handler
+
+func (t teeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range t {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t teeHandler) Handle(ctx context.Context, r slog.Record) error {
+	for _, h := range t {
+		if !h.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := h.Handle(ctx, r.Clone()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	hs := make(teeHandler, len(t))
+	for i, h := range t {
+		hs[i] = h.WithAttrs(attrs)
+	}
+	return hs
+}
+
+func (t teeHandler) WithGroup(name string) slog.Handler {
+	hs := make(teeHandler, len(t))
+	for i, h := range t {
+		hs[i] = h.WithGroup(name)
+	}
+	return hs
+}
@@ -0,0 +1,123 @@
+//go:build example
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/owarai/reopen"
+)
+
+// Note:
+// this example is a standalone module guarded by the example build tag, run it with
+//
+//	go run -tags example .
+//
+// use logrotate to test:
+//
+// add config file to $HOME/.config/logrotate.conf
+// content like this: {{xxx}} aims to be replaced to true value.
+//
+//	{{your_logfile_dir}}/example-zerolog.log {{your_logfile_dir}}/example-zerolog.log.wf {
+//	   hourly
+//	   rotate 8
+//	   size 1M
+//	   missingok
+//	   notifempty
+//	   compress
+//	   sharedscripts
+//	   postrotate
+//	   /bin/killall -USR1 {{your_process_name}}
+//	   endscript
+//	}
+//
+// run this program and run logrotate periodically.
+//
+//	logrotate $HOME/.config/logrotate.conf --state $HOME/.config/logrotate-state --verbose
+func main() {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+		defer cancel()
+
+		select {
+		case <-time.After(5 * time.Minute):
+			return
+		case sig := <-signals:
+			fmt.Printf("terminating via signal: %s", sig)
+			return
+		}
+	}()
+
+	// Both files are reopened together when the process receives USR1.
+	group := reopen.NewRotationGroup(syscall.SIGUSR1)
+	defer group.Close()
+
+	logger, err := newLogger(group, "example-zerolog")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	zerologLog(ctx, logger, time.Millisecond)
+}
+
+func zerologLog(ctx context.Context, logger zerolog.Logger, writeInterval time.Duration) {
+	url := "www.google.com"
+	for {
+		time.Sleep(writeInterval)
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		logger.Debug().Str("url", url).Int("attempt", 1).Dur("backoff", 1).
+			Msg("Operation execution successful")
+		if rand.Intn(10) >= 5 {
+			logger.Error().Str("url", url).Int("attempt", 3).Dur("backoff", 1).
+				Msg("Failed to fetch URL")
+		}
+	}
+}
+
+func newLogger(group *reopen.RotationGroup, destName string) (zerolog.Logger, error) {
+	logDebugs, err := group.New(destName+".log", reopen.WithFileMode(0644))
+	if err != nil {
+		return zerolog.Logger{}, err
+	}
+	logErrors, err := group.New(destName+".log.wf", reopen.WithFileMode(0644))
+	if err != nil {
+		return zerolog.Logger{}, err
+	}
+
+	out := zerolog.MultiLevelWriter(
+		levelWriter{w: logDebugs, min: zerolog.DebugLevel},
+		levelWriter{w: logErrors, min: zerolog.ErrorLevel},
+	)
+	return zerolog.New(out).Level(zerolog.DebugLevel).With().Timestamp().Logger(), nil
+}
+
+// levelWriter only writes the events whose level is at least min.
+type levelWriter struct {
+	w   *reopen.ReopenableWriteSyncer
+	min zerolog.Level
+}
+
+func (lw levelWriter) Write(p []byte) (int, error) {
+	return lw.w.Write(p)
+}
+
+func (lw levelWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if level < lw.min {
+		return len(p), nil
+	}
+	return lw.w.Write(p)
+}
@@ -0,0 +1,165 @@
+package reopen
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// filterFields removes the top-level fields of WithFieldFilter from the JSON object p.
+// Payloads which are not JSON objects are returned as is.
+func (ws *ReopenableWriteSyncer) filterFields(p []byte) []byte {
+	if ws.fastFieldFilter {
+		return filterFieldsFast(p, ws.filteredFields)
+	}
+	body := bytes.TrimRight(p, "\n")
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(body, &obj); err != nil || obj == nil {
+		return p
+	}
+	removed := false
+	for field := range ws.filteredFields {
+		if _, ok := obj[field]; ok {
+			delete(obj, field)
+			removed = true
+		}
+	}
+	if !removed {
+		return p
+	}
+	out, err := json.Marshal(obj)
+	if err != nil {
+		return p
+	}
+	return append(out, p[len(body):]...)
+}
+
+// filterFieldsFast removes the top-level fields of p whose key is in fields, the keys holding escape sequences
+// are unescaped before the comparison.
+// It scans p once without decoding it and keeps the order of the other fields.
+// Payloads which are not well-formed JSON objects are returned as is.
+func filterFieldsFast(p []byte, fields map[string]bool) []byte {
+	open := skipSpace(p, 0)
+	if open >= len(p) || p[open] != '{' {
+		return p
+	}
+	var kept [][2]int // spans of the fields kept, from the key to the end of the value
+	removed := false
+	i := skipSpace(p, open+1)
+	if i < len(p) && p[i] == '}' {
+		return p
+	}
+	for {
+		if i >= len(p) || p[i] != '"' {
+			return p
+		}
+		keyEnd := skipString(p, i)
+		if keyEnd < 0 {
+			return p
+		}
+		colon := skipSpace(p, keyEnd)
+		if colon >= len(p) || p[colon] != ':' {
+			return p
+		}
+		valueEnd := skipValue(p, skipSpace(p, colon+1))
+		if valueEnd < 0 {
+			return p
+		}
+		key := p[i+1 : keyEnd-1]
+		match := fields[string(key)]
+		if bytes.IndexByte(key, '\\') >= 0 {
+			// an escaped key, e.g. "pass\u0077ord", is matched once unescaped
+			var name string
+			if err := json.Unmarshal(p[i:keyEnd], &name); err != nil {
+				return p
+			}
+			match = fields[name]
+		}
+		if match {
+			removed = true
+		} else {
+			kept = append(kept, [2]int{i, valueEnd})
+		}
+		i = skipSpace(p, valueEnd)
+		if i >= len(p) {
+			return p
+		}
+		if p[i] == '}' {
+			break
+		}
+		if p[i] != ',' {
+			return p
+		}
+		i = skipSpace(p, i+1)
+	}
+	if !removed {
+		return p
+	}
+	out := make([]byte, 0, len(p))
+	out = append(out, p[:open+1]...)
+	for n, span := range kept {
+		if n > 0 {
+			out = append(out, ',')
+		}
+		out = append(out, p[span[0]:span[1]]...)
+	}
+	return append(out, p[i:]...)
+}
+
+func skipSpace(p []byte, i int) int {
+	for i < len(p) && (p[i] == ' ' || p[i] == '\t' || p[i] == '\n' || p[i] == '\r') {
+		i++
+	}
+	return i
+}
+
+// skipString returns the index after the JSON string starting at p[i], or -1.
+func skipString(p []byte, i int) int {
+	for i++; i < len(p); i++ {
+		switch p[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// skipValue returns the index after the JSON value starting at p[i], or -1.
+func skipValue(p []byte, i int) int {
+	if i >= len(p) {
+		return -1
+	}
+	switch p[i] {
+	case '"':
+		return skipString(p, i)
+	case '{', '[':
+		depth := 0
+		for ; i < len(p); i++ {
+			switch p[i] {
+			case '"':
+				end := skipString(p, i)
+				if end < 0 {
+					return -1
+				}
+				i = end - 1
+			case '{', '[':
+				depth++
+			case '}', ']':
+				if depth--; depth == 0 {
+					return i + 1
+				}
+			}
+		}
+		return -1
+	default:
+		start := i
+		for i < len(p) && p[i] != ',' && p[i] != '}' && p[i] != ']' && p[i] != ' ' && p[i] != '\t' && p[i] != '\n' && p[i] != '\r' {
+			i++
+		}
+		if i == start {
+			return -1
+		}
+		return i
+	}
+}
@@ -0,0 +1,70 @@
+package reopen_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/owarai/reopen"
+	"github.com/owarai/reopen/reopentest"
+)
+
+func TestFieldFilter(t *testing.T) {
+	payloads := []string{
+		`{"level":"info","msg":"login","user":"alice","password":"SECRET-1"}`,
+		`{"level":"info","msg":"no sensitive field"}`,
+		`{"token":"SECRET-2","nested":{"password":"kept, not top-level"},"msg":"token first"}`,
+		`{"msg":"escaped key","pass\u0077ord":"SECRET-3"}`,
+		`{"msg":"spaces" , "ssn" : "SECRET-4" }`,
+		`{"password":"SECRET-5","password":"SECRET-6"}`,
+		`{}`,
+	}
+	filters := map[string]func(...string) reopen.Option{
+		"encoding/json": reopen.WithFieldFilter,
+		"fast":          reopen.WithFastFieldFilter,
+	}
+	for name, filter := range filters {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log")
+			ws, err := reopen.NewWithOptions(path, filter("password", "token", "ssn"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, p := range payloads {
+				if _, err := ws.Write([]byte(p + "\n")); err != nil {
+					t.Fatal(err)
+				}
+			}
+			reopentest.MustClose(t, ws)
+			b, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Contains(b, []byte("SECRET")) {
+				t.Errorf("a filtered field reached the disk:\n%s", b)
+			}
+			lines := 0
+			for s := bufio.NewScanner(bytes.NewReader(b)); s.Scan(); lines++ {
+				var obj map[string]json.RawMessage
+				if err := json.Unmarshal(s.Bytes(), &obj); err != nil {
+					t.Errorf("invalid JSONL line %q: %v", s.Text(), err)
+				}
+				for _, field := range []string{"password", "token", "ssn"} {
+					if _, ok := obj[field]; ok {
+						t.Errorf("field %s in %q", field, s.Text())
+					}
+				}
+			}
+			if lines != len(payloads) {
+				t.Errorf("%d lines, want %d", lines, len(payloads))
+			}
+			if !strings.Contains(string(b), `"kept, not top-level"`) {
+				t.Errorf("nested field removed:\n%s", b)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package reopen
+
+import (
+	"io/fs"
+	"os"
+)
+
+// FileSystem opens and renames the log files, see WithFileSystem.
+type FileSystem interface {
+	OpenFile(name string, flag int, perm os.FileMode) (*os.File, error)
+	Rename(oldpath, newpath string) error
+}
+
+// osFileSystem is the default FileSystem, backed by the os package.
+type osFileSystem struct{}
+
+func (osFileSystem) OpenFile(name string, flag int, perm os.FileMode) (*os.File, error) {
+	return os.OpenFile(name, flag, perm)
+}
+
+func (osFileSystem) Rename(oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+func (osFileSystem) DirFS(dir string) fs.FS {
+	return os.DirFS(dir)
+}
@@ -0,0 +1,47 @@
+package reopen
+
+import (
+	"io"
+	"os"
+)
+
+// Forward copies count bytes of the current file of src starting at offset to the current file of dst,
+// with sendfile on linux so the bytes do not go through user space, and fsyncs both files.
+// A negative count copies up to the end of the file. The bytes bypass the write path of dst:
+// its transformations and the write counters of Stats do not apply.
+func Forward(src, dst *ReopenableWriteSyncer, offset, count int64) (int64, error) {
+	in, err := os.Open(src.CurrentFilePath())
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+	if count < 0 {
+		fi, err := in.Stat()
+		if err != nil {
+			return 0, err
+		}
+		if count = fi.Size() - offset; count < 0 {
+			count = 0
+		}
+	}
+	out := dst.acquire()
+	if out == nil {
+		return 0, os.ErrClosed
+	}
+	n, err := forward(out.File, in, offset, count)
+	out.written.Add(n)
+	dst.physicalBytes.Add(n)
+	if err == nil {
+		err = out.Sync()
+	}
+	out.release()
+	if err != nil {
+		return n, err
+	}
+	return n, src.syncFile()
+}
+
+// copyRange copies count bytes of in starting at offset to out through a user space buffer.
+func copyRange(out, in *os.File, offset, count int64) (int64, error) {
+	return io.Copy(out, io.NewSectionReader(in, offset, count))
+}
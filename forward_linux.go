@@ -0,0 +1,37 @@
+package reopen
+
+import (
+	"os"
+	"syscall"
+)
+
+// forward copies count bytes of in starting at offset to out with sendfile,
+// falling back to a copy when the kernel refuses it, e.g. for an O_APPEND destination on older kernels.
+func forward(out, in *os.File, offset, count int64) (int64, error) {
+	var written int64
+	for written < count {
+		chunk := count - written
+		if chunk > 1<<30 {
+			chunk = 1 << 30
+		}
+		off := offset + written
+		n, err := syscall.Sendfile(int(out.Fd()), int(in.Fd()), &off, int(chunk))
+		if n > 0 {
+			written += int64(n)
+		}
+		if err == syscall.EINTR || err == syscall.EAGAIN {
+			continue
+		}
+		if err == syscall.EINVAL || err == syscall.ENOSYS {
+			m, err := copyRange(out, in, offset+written, count-written)
+			return written + m, err
+		}
+		if err != nil {
+			return written, os.NewSyscallError("sendfile", err)
+		}
+		if n == 0 {
+			break // end of in
+		}
+	}
+	return written, nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package reopen
+
+import "os"
+
+// forward copies count bytes of in starting at offset to out, sendfile is only used on linux.
+func forward(out, in *os.File, offset, count int64) (int64, error) {
+	return copyRange(out, in, offset, count)
+}
@@ -0,0 +1,72 @@
+package reopen_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/owarai/reopen"
+)
+
+func TestForward(t *testing.T) {
+	const src = "0123456789"
+	tests := []struct {
+		name          string
+		offset, count int64
+		want          string
+	}{
+		{"whole file", 0, -1, src},
+		{"range", 2, 5, "23456"},
+		{"up to the end", 7, -1, "789"},
+		{"offset past the end", 20, -1, ""},
+		{"empty range", 3, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			in, err := reopen.NewWithOptions(filepath.Join(dir, "in.log"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer in.Close()
+			dstPath := filepath.Join(dir, "out.log")
+			out, err := reopen.NewWithOptions(dstPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer out.Close()
+			writeLines(t, in, src)
+			if _, err := out.Write([]byte("head:")); err != nil {
+				t.Fatal(err)
+			}
+			n, err := reopen.Forward(in, out, tt.offset, tt.count)
+			if err != nil || n != int64(len(tt.want)) {
+				t.Fatalf("Forward returned %d, %v, want %d", n, err, len(tt.want))
+			}
+			// the forwarded bytes are appended after those written to dst
+			if b, err := os.ReadFile(dstPath); err != nil || string(b) != "head:"+tt.want {
+				t.Errorf("destination holds %q, %v, want %q", b, err, "head:"+tt.want)
+			}
+		})
+	}
+}
+
+func TestForwardClosedDestination(t *testing.T) {
+	dir := t.TempDir()
+	in, err := reopen.NewWithOptions(filepath.Join(dir, "in.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	out, err := reopen.NewWithOptions(filepath.Join(dir, "out.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeLines(t, in, "line")
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reopen.Forward(in, out, 0, -1); err != os.ErrClosed {
+		t.Errorf("Forward to a closed writer returned %v, want %v", err, os.ErrClosed)
+	}
+}
@@ -0,0 +1,53 @@
+package reopen
+
+import (
+	"encoding/binary"
+	"strconv"
+)
+
+// Framer frames every payload as Header(len(payload)), the payload, then Trailer, see WithFramer.
+type Framer struct {
+	Header  func(payloadLen int) []byte
+	Trailer []byte
+}
+
+// NetstringFramer frames the payloads as netstrings, e.g. "5:hello,".
+func NetstringFramer() Framer {
+	return Framer{
+		Header: func(payloadLen int) []byte {
+			return append(strconv.AppendInt(nil, int64(payloadLen), 10), ':')
+		},
+		Trailer: []byte(","),
+	}
+}
+
+// LengthPrefixFramer prefixes the payloads with their length as an unsigned integer of width bytes in order.
+// width must be 1, 2, 4 or 8.
+func LengthPrefixFramer(width int, order binary.ByteOrder) Framer {
+	var header func(payloadLen int) []byte
+	switch width {
+	case 1:
+		header = func(n int) []byte { return []byte{byte(n)} }
+	case 2:
+		header = func(n int) []byte {
+			b := make([]byte, 2)
+			order.PutUint16(b, uint16(n))
+			return b
+		}
+	case 4:
+		header = func(n int) []byte {
+			b := make([]byte, 4)
+			order.PutUint32(b, uint32(n))
+			return b
+		}
+	case 8:
+		header = func(n int) []byte {
+			b := make([]byte, 8)
+			order.PutUint64(b, uint64(n))
+			return b
+		}
+	default:
+		panic("reopen: invalid length prefix width " + strconv.Itoa(width))
+	}
+	return Framer{Header: header}
+}
@@ -0,0 +1,38 @@
+package reopen
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// DirFileSystem is a FileSystem which can also read its directories, FS reads the directory of the log file
+// through it. The default FileSystem is one, as is reopentest.InMemoryFS.
+type DirFileSystem interface {
+	FileSystem
+	// DirFS returns a read-only fs.FS rooted at dir.
+	DirFS(dir string) fs.FS
+}
+
+// FS returns a read-only fs.FS rooted at the directory of the log file, read through the FileSystem of
+// WithFileSystem if it is a DirFileSystem and through the os package otherwise. The code reading the logs can
+// then be tested against the same in-memory files as the writer, e.g. with reopentest.InMemoryFS.
+func (ws *ReopenableWriteSyncer) FS() fs.FS {
+	if ws.noFile() {
+		return closedFS{}
+	}
+	ws.reloadMu.Lock()
+	dir := filepath.Dir(ws.filePath)
+	ws.reloadMu.Unlock()
+	if dfs, ok := ws.fileSystem.(DirFileSystem); ok {
+		return dfs.DirFS(dir)
+	}
+	return os.DirFS(dir)
+}
+
+// closedFS is the FS of a writer without file.
+type closedFS struct{}
+
+func (closedFS) Open(name string) (fs.File, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: os.ErrClosed}
+}
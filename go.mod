@@ -1,3 +1,3 @@
 module github.com/owarai/reopen
 
-go 1.15
+go 1.20
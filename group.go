@@ -0,0 +1,112 @@
+package reopen
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"runtime/pprof"
+	"sync"
+	"syscall"
+)
+
+// RotationGroup reopens several ReopenableWriteSyncers on the same signals,
+// so a single logrotate postrotate command covers all the files of a process.
+type RotationGroup struct {
+	reopenSig chan os.Signal
+	closing   chan struct{}
+	closeOnce sync.Once
+
+	mu      sync.Mutex
+	members []*ReopenableWriteSyncer
+}
+
+// NewRotationGroup create a RotationGroup monitoring sig(default is USR1).
+func NewRotationGroup(sig ...os.Signal) *RotationGroup {
+	if len(sig) == 0 {
+		sig = append(sig, syscall.SIGUSR1)
+	}
+	g := &RotationGroup{
+		reopenSig: make(chan os.Signal, 1),
+		closing:   make(chan struct{}),
+	}
+	signal.Notify(g.reopenSig, sig...)
+	go pprof.Do(context.Background(), pprof.Labels("reopen", "group-watch"), func(context.Context) { g.watch() })
+	return g
+}
+
+// New create a writeSyncer for file which is reopened by the group instead of by its own signals.
+// WithSignals is ignored for writers created by the group, the errors of the reopens triggered by the signals of
+// the group go to the WithErrorHandler of each writer.
+func (g *RotationGroup) New(file string, opts ...Option) (*ReopenableWriteSyncer, error) {
+	opts = append(opts, func(ws *ReopenableWriteSyncer) {
+		ws.grouped = true
+	})
+	ws, err := NewWithOptions(file, opts...)
+	if err != nil {
+		return nil, err
+	}
+	g.mu.Lock()
+	g.members = append(g.members, ws)
+	g.mu.Unlock()
+	return ws, nil
+}
+
+// Reopen reopens every writer of the group and returns the first error encountered.
+func (g *RotationGroup) Reopen() error {
+	return g.rotate(TriggerManual, nil)
+}
+
+// Close stops monitoring the signals and closes every writer of the group,
+// it returns os.ErrClosed if the group is already closed.
+func (g *RotationGroup) Close() error {
+	closed := true
+	g.closeOnce.Do(func() {
+		closed = false
+		signal.Stop(g.reopenSig)
+		close(g.closing)
+	})
+	if closed {
+		return os.ErrClosed
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var firstErr error
+	for _, ws := range g.members {
+		if err := ws.Close(); err != nil && err != os.ErrClosed && firstErr == nil {
+			firstErr = err
+		}
+	}
+	g.members = nil
+	return firstErr
+}
+
+func (g *RotationGroup) watch() {
+	for {
+		select {
+		case <-g.closing:
+			return
+		case sig := <-g.reopenSig:
+			_ = g.rotate(TriggerSignal, sig) // the errors are reported to the error handler of each writer
+		}
+	}
+}
+
+func (g *RotationGroup) rotate(trigger RotationTrigger, sig os.Signal) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var firstErr error
+	for _, ws := range g.members {
+		err := ws.rotate(trigger, sig)
+		if err == nil || err == os.ErrClosed {
+			continue
+		}
+		if trigger == TriggerSignal {
+			ws.handleError(err)
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,59 @@
+package reopen_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/owarai/reopen"
+)
+
+// failingFS fails every open once its file has been opened, so the reopens fail.
+type failingFS struct{ opened bool }
+
+func (fs *failingFS) OpenFile(name string, flag int, perm os.FileMode) (*os.File, error) {
+	if fs.opened {
+		return nil, errors.New("open refused")
+	}
+	fs.opened = true
+	return os.OpenFile(name, flag, perm)
+}
+
+func (fs *failingFS) Rename(oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+func TestRotationGroupReportsSignalErrors(t *testing.T) {
+	g := reopen.NewRotationGroup(syscall.SIGWINCH)
+	errs := make(chan error, 1)
+	_, err := g.New(filepath.Join(t.TempDir(), "app.log"), reopen.WithFileSystem(&failingFS{}),
+		reopen.WithErrorHandler(func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Kill(os.Getpid(), syscall.SIGWINCH); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errs:
+		if err.Error() != "open refused" {
+			t.Errorf("error handler got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("the failed reopen was not reported")
+	}
+	if err := g.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Close(); err != os.ErrClosed {
+		t.Errorf("second Close returned %v, want os.ErrClosed", err)
+	}
+}
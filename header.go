@@ -0,0 +1,44 @@
+package reopen
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+)
+
+// writeHeader writes the header of WithFileHeader and WithLokiLabels to f, which is empty and not yet the current file.
+func (ws *ReopenableWriteSyncer) writeHeader(f *logFile) {
+	var n int
+	var err error
+	physical := 0
+	if f.enc != nil {
+		n, physical, err = f.enc.write(f.File, ws.header)
+	} else if ws.directAlignment > 0 {
+		n, err = ws.writeAligned(f, ws.header)
+	} else {
+		n, err = f.Write(ws.header)
+	}
+	if f.enc == nil {
+		physical = n
+	}
+	f.written.Add(int64(physical))
+	f.lines.Add(int64(bytes.Count(ws.header[:n], []byte{'\n'})))
+	ws.physicalBytes.Add(int64(physical))
+	if err != nil {
+		ws.handleError(fmt.Errorf("reopen: write header %s: %w", f.Name(), err))
+	}
+}
+
+// ensureEmpty renames the file at the active path to a backup named by the RotationNamer when f is not empty,
+// and returns a fresh file opened in its place.
+func (ws *ReopenableWriteSyncer) ensureEmpty(f *os.File, flag int) (*os.File, error) {
+	fi, err := f.Stat()
+	if err != nil || fi.Size() == 0 {
+		return f, nil
+	}
+	_ = f.Close()
+	if err := ws.fileSystem.Rename(ws.activePath(), ws.namer.BackupName(ws.filePath, ws.clock.Now())); err != nil {
+		return nil, err
+	}
+	return ws.fileSystem.OpenFile(ws.activePath(), flag, ws.fileMode)
+}
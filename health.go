@@ -0,0 +1,26 @@
+package reopen
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// HealthHandler returns a handler reporting the health of the writer as a JSON object {"state":...,"path":...},
+// with the status 200 while the writer is open, rotating or idle and 503 otherwise. With ?debug=true the object
+// also holds the DebugDump under "debug", for support incident investigations.
+func (ws *ReopenableWriteSyncer) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state := ws.State()
+		body := map[string]any{"state": state.String(), "path": ws.CurrentFilePath()}
+		if r.URL.Query().Get("debug") == "true" {
+			body["debug"] = ws.DebugDump()
+		}
+		status := http.StatusOK
+		if ws.noFile() || (state != StateOpen && state != StateRotating && state != StateIdle) {
+			status = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(body)
+	})
+}
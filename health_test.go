@@ -0,0 +1,39 @@
+package reopen_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/owarai/reopen"
+	"github.com/owarai/reopen/reopentest"
+)
+
+func TestHealthHandler(t *testing.T) {
+	ws, err := reopen.NewWithOptions(filepath.Join(t.TempDir(), "app.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(url string) (int, map[string]any) {
+		rec := httptest.NewRecorder()
+		ws.HealthHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		var body map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: %v", url, err)
+		}
+		return rec.Code, body
+	}
+	if code, body := get("/health"); code != http.StatusOK || body["state"] != "open" || body["debug"] != nil {
+		t.Errorf("open writer: %d %v", code, body)
+	}
+	code, body := get("/health?debug=true")
+	if debug, _ := body["debug"].(map[string]any); code != http.StatusOK || debug["path"] != ws.CurrentFilePath() {
+		t.Errorf("debug dump: %d %v", code, body)
+	}
+	reopentest.MustClose(t, ws)
+	if code, body := get("/health?debug=true"); code != http.StatusServiceUnavailable || body["state"] != "closed" {
+		t.Errorf("closed writer: %d %v", code, body)
+	}
+}
@@ -0,0 +1,59 @@
+package reopen
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// httpTrigger is the endpoint configured by WithHTTPRotationTrigger.
+type httpTrigger struct {
+	mux   *http.ServeMux
+	path  string
+	token string
+}
+
+// register registers h on the mux at the path of t, it fails instead of letting ServeMux panic
+// when the path is already registered, and on an empty token which every "Bearer " header would match.
+func (t *httpTrigger) register(h http.Handler) error {
+	if t.path == "" {
+		return fmt.Errorf("reopen: invalid rotation trigger path %q", t.path)
+	}
+	if t.token == "" {
+		return errors.New("reopen: empty rotation trigger token")
+	}
+	if _, pattern := t.mux.Handler(&http.Request{Method: http.MethodPost, URL: &url.URL{Path: t.path}}); pattern == t.path {
+		return fmt.Errorf("reopen: rotation trigger path %q is already registered", t.path)
+	}
+	t.mux.Handle(t.path, h)
+	return nil
+}
+
+// rotationHandler returns the handler reopening the file on an authenticated POST request.
+// It answers with the path of the new file, or of the file which would be reopened with ?dry_run=true.
+func (ws *ReopenableWriteSyncer) rotationHandler(token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("dry_run") == "true" {
+			fmt.Fprintf(w, "dry run: would reopen %s\n", ws.CurrentFilePath())
+			return
+		}
+		if err := ws.Reopen(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprintln(w, ws.CurrentFilePath())
+	})
+}
@@ -0,0 +1,65 @@
+package reopen_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/owarai/reopen"
+)
+
+func TestHTTPRotationTriggerConflict(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rotate", func(http.ResponseWriter, *http.Request) {})
+	path := filepath.Join(t.TempDir(), "app.log")
+	if ws, err := reopen.NewWithOptions(path, reopen.WithHTTPRotationTrigger(mux, "/rotate", "secret")); err == nil {
+		_ = ws.Close()
+		t.Fatal("registering an already registered path succeeded")
+	}
+
+	if ws, err := reopen.NewWithOptions(path, reopen.WithHTTPRotationTrigger(mux, "/logs/rotate", "")); err == nil {
+		_ = ws.Close()
+		t.Fatal("registering an empty token succeeded")
+	}
+}
+
+func TestHTTPRotationTriggerAuthorization(t *testing.T) {
+	mux := http.NewServeMux()
+	ws, err := reopen.NewWithOptions(filepath.Join(t.TempDir(), "app.log"),
+		reopen.WithHTTPRotationTrigger(mux, "/logs/rotate", "secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	tests := []struct {
+		name          string
+		method        string
+		authorization string
+		want          int
+	}{
+		{"valid token", http.MethodPost, "Bearer secret", http.StatusOK},
+		{"missing token", http.MethodPost, "", http.StatusUnauthorized},
+		{"empty token", http.MethodPost, "Bearer ", http.StatusUnauthorized},
+		{"wrong token", http.MethodPost, "Bearer guess", http.StatusUnauthorized},
+		{"not a bearer token", http.MethodPost, "Basic secret", http.StatusUnauthorized},
+		{"not a POST", http.MethodGet, "Bearer secret", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := ws.Stats().Rotations
+			req := httptest.NewRequest(tt.method, "/logs/rotate", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("returned %d: %s, want %d", rec.Code, rec.Body, tt.want)
+			}
+			if rotated := ws.Stats().Rotations != before; rotated != (tt.want == http.StatusOK) {
+				t.Errorf("rotated is %v", rotated)
+			}
+		})
+	}
+}
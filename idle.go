@@ -0,0 +1,76 @@
+package reopen
+
+import (
+	"os"
+	"time"
+)
+
+// closeInactive closes the file once no write happened for inactivityTimeout, see WithInactivityClose.
+func (ws *ReopenableWriteSyncer) closeInactive() {
+	ticker := time.NewTicker(ws.idleTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ws.closing:
+			return
+		case <-ticker.C:
+			if !ws.idle.Load() && ws.inactiveFor() >= ws.idleTimeout {
+				ws.goIdle()
+			}
+		}
+	}
+}
+
+func (ws *ReopenableWriteSyncer) inactiveFor() time.Duration {
+	return ws.clock.Now().Sub(time.Unix(0, ws.lastWrite.Load()))
+}
+
+// goIdle retires and closes the current file, it stays the current file so acquire can tell the writer is idle.
+func (ws *ReopenableWriteSyncer) goIdle() {
+	ws.reloadMu.Lock()
+	defer ws.reloadMu.Unlock()
+	if ws.closed() || ws.idle.Load() || ws.inactiveFor() < ws.idleTimeout {
+		return
+	}
+	f := ws.getFile()
+	ws.idle.Store(true) // before retiring f, so the writers finding it retired open the file again
+	f.retire(ws.drainTimeout)
+	// synced so Sync can do nothing while idle
+	ws.pendingSync.Store(false)
+	if f.enc != nil {
+		if err := ws.flushEncrypted(f); err != nil {
+			ws.handleError(err)
+		}
+	}
+	if err := f.Sync(); err != nil {
+		ws.handleError(err)
+	}
+	if ws.activeSuffix != "" {
+		f.idleStat, _ = f.Stat()
+	}
+	_ = ws.closeFile(f)
+	ws.setState(StateIdle)
+}
+
+// wakeUp opens the file again after goIdle, it reports false if the writer has been closed or the open failed.
+func (ws *ReopenableWriteSyncer) wakeUp() bool {
+	ws.reloadMu.Lock()
+	defer ws.reloadMu.Unlock()
+	if ws.closed() {
+		return false
+	}
+	if !ws.idle.Load() {
+		return true
+	}
+	if err := ws.reload(); err == errReopenSuperseded {
+		return true // woken up by a reopen while the retry was waiting
+	} else if err != nil {
+		if err != os.ErrClosed {
+			ws.handleError(err)
+		}
+		return false
+	}
+	ws.lastWrite.Store(ws.clock.Now().UnixNano())
+	ws.setState(StateOpen)
+	return true
+}
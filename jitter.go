@@ -0,0 +1,19 @@
+package reopen
+
+import (
+	"math/rand"
+	"time"
+)
+
+// waitJitter sleeps for a random duration in [0, maxJitter] of WithRotationJitter, or until the writer is closed.
+func (ws *ReopenableWriteSyncer) waitJitter() {
+	if ws.maxJitter <= 0 {
+		return
+	}
+	timer := time.NewTimer(time.Duration(rand.Int63n(int64(ws.maxJitter) + 1)))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ws.closing:
+	}
+}
@@ -0,0 +1,45 @@
+package journald
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// tempDir holds the temporary files of the entries too large for a datagram, journald only accepts
+// the descriptors of files on a temporary file system.
+const tempDir = "/dev/shm"
+
+// tooLarge reports whether err means the entry does not fit in one datagram.
+func tooLarge(err error) bool {
+	return errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS)
+}
+
+// sendFile writes entry to an unlinked temporary file and passes its descriptor to journald
+// with SCM_RIGHTS, journald then reads the entry from the file.
+func (ws *JournaldWriteSyncer) sendFile(entry []byte) error {
+	f, err := os.CreateTemp(tempDir, "journald-")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := os.Remove(f.Name()); err != nil {
+		return err
+	}
+	if _, err := f.Write(entry); err != nil {
+		return err
+	}
+	// WriteMsgUnix refuses the connected datagram sockets, so sendmsg is called on the raw connection.
+	rc, err := ws.conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var sendErr error
+	if err := rc.Write(func(fd uintptr) bool {
+		sendErr = syscall.Sendmsg(int(fd), nil, syscall.UnixRights(int(f.Fd())), nil, 0)
+		return sendErr != syscall.EAGAIN
+	}); err != nil {
+		return err
+	}
+	return sendErr
+}
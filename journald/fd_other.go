@@ -0,0 +1,13 @@
+//go:build !linux
+
+package journald
+
+import "errors"
+
+func tooLarge(error) bool {
+	return false
+}
+
+func (ws *JournaldWriteSyncer) sendFile([]byte) error {
+	return errors.New("journald: entry too large for a datagram")
+}
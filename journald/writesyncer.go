@@ -0,0 +1,155 @@
+// Package journald implements a zapcore.WriteSyncer which sends the log lines to the systemd journal
+// over its native protocol, for the systemd-managed services preferring the journal to log files.
+//
+// There is no file behind this WriteSyncer, so unlike reopen.ReopenableWriteSyncer it does not monitor any signal.
+package journald
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// DefaultSocketPath is the native protocol socket of systemd-journald.
+const DefaultSocketPath = "/run/systemd/journal/socket"
+
+// ErrClosed is returned by Write and Close after Close.
+var ErrClosed = errors.New("journald: write syncer closed")
+
+// Option configures a JournaldWriteSyncer created by NewJournaldWriteSyncer.
+type Option func(ws *JournaldWriteSyncer)
+
+// WithSocketPath specify the socket of journald(default is /run/systemd/journal/socket).
+func WithSocketPath(path string) Option {
+	return func(ws *JournaldWriteSyncer) {
+		ws.socketPath = path
+	}
+}
+
+// WithPriority specify the syslog priority of the entries, from 0(emerg) to 7(debug)(default is none,
+// journald then uses 6(info)).
+func WithPriority(priority int) Option {
+	return func(ws *JournaldWriteSyncer) {
+		ws.priority = strconv.Itoa(priority)
+	}
+}
+
+// WithField adds the field name=value to every entry, name must be made of uppercase letters,
+// digits and underscores and must not start with an underscore.
+func WithField(name, value string) Option {
+	return func(ws *JournaldWriteSyncer) {
+		ws.fields = append(ws.fields, [2]string{name, value})
+	}
+}
+
+// JournaldWriteSyncer sends every Write as one journal entry whose MESSAGE is the written bytes
+// without their trailing newline. The entries too large for one datagram of the socket are passed to journald
+// as a descriptor of a temporary file in /dev/shm, as sd_journal_send does.
+type JournaldWriteSyncer struct {
+	socketPath string
+	identifier string
+	priority   string
+	fields     [][2]string
+
+	conn *net.UnixConn
+}
+
+// NewJournaldWriteSyncer create a JournaldWriteSyncer whose entries have the SYSLOG_IDENTIFIER identifier.
+func NewJournaldWriteSyncer(identifier string, opts ...Option) (*JournaldWriteSyncer, error) {
+	ws := &JournaldWriteSyncer{socketPath: DefaultSocketPath, identifier: identifier}
+	for _, opt := range opts {
+		opt(ws)
+	}
+	for _, f := range ws.fields {
+		if !validFieldName(f[0]) {
+			return nil, errors.New("journald: invalid field name " + strconv.Quote(f[0]))
+		}
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: ws.socketPath, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	ws.conn = conn
+	return ws, nil
+}
+
+// Write sends p as the MESSAGE of a journal entry.
+func (ws *JournaldWriteSyncer) Write(p []byte) (int, error) {
+	var b bytes.Buffer
+	appendField(&b, "MESSAGE", bytes.TrimSuffix(p, []byte("\n")))
+	if ws.identifier != "" {
+		appendField(&b, "SYSLOG_IDENTIFIER", []byte(ws.identifier))
+	}
+	if ws.priority != "" {
+		appendField(&b, "PRIORITY", []byte(ws.priority))
+	}
+	for _, f := range ws.fields {
+		appendField(&b, f[0], []byte(f[1]))
+	}
+	if _, err := ws.conn.Write(b.Bytes()); err != nil {
+		if errors.Is(err, net.ErrClosed) {
+			return 0, ErrClosed
+		}
+		if !tooLarge(err) {
+			return 0, err
+		}
+		if err := ws.sendFile(b.Bytes()); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Sync does nothing, the durability of the entries is up to journald.
+func (ws *JournaldWriteSyncer) Sync() error {
+	return nil
+}
+
+// Reopen does nothing, it lets JournaldWriteSyncer replace a reopen.ReopenableWriteSyncer
+// whose rotation signals are then ignored.
+func (ws *JournaldWriteSyncer) Reopen() error {
+	return nil
+}
+
+// Close closes the socket.
+func (ws *JournaldWriteSyncer) Close() error {
+	if err := ws.conn.Close(); err != nil {
+		if errors.Is(err, net.ErrClosed) {
+			return ErrClosed
+		}
+		return err
+	}
+	return nil
+}
+
+// appendField appends the field name=value to b, using the binary-safe form of the protocol
+// when value holds a newline.
+func appendField(b *bytes.Buffer, name string, value []byte) {
+	b.WriteString(name)
+	if bytes.IndexByte(value, '\n') < 0 {
+		b.WriteByte('=')
+		b.Write(value)
+	} else {
+		var size [8]byte
+		binary.LittleEndian.PutUint64(size[:], uint64(len(value)))
+		b.WriteByte('\n')
+		b.Write(size[:])
+		b.Write(value)
+	}
+	b.WriteByte('\n')
+}
+
+func validFieldName(name string) bool {
+	if name == "" || strings.HasPrefix(name, "_") {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
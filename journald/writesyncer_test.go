@@ -0,0 +1,61 @@
+package journald_test
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/owarai/reopen/journald"
+)
+
+func TestWriteLargeEntry(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "journal.socket")
+	server, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	ws, err := journald.NewJournaldWriteSyncer("test", journald.WithSocketPath(socket))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+
+	msg := bytes.Repeat([]byte("stack trace line\n"), 1<<16)
+	if n, err := ws.Write(msg); err != nil || n != len(msg) {
+		t.Fatalf("Write returned %d, %v", n, err)
+	}
+
+	oob := make([]byte, syscall.CmsgSpace(4))
+	n, oobn, _, _, err := server.ReadMsgUnix(make([]byte, 1), oob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Fatalf("received a %d bytes datagram, want only a descriptor", n)
+	}
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("control messages %v, %v", msgs, err)
+	}
+	fds, err := syscall.ParseUnixRights(&msgs[0])
+	if err != nil || len(fds) != 1 {
+		t.Fatalf("descriptors %v, %v", fds, err)
+	}
+	f := os.NewFile(uintptr(fds[0]), "entry")
+	defer f.Close()
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	entry, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(entry, []byte("MESSAGE\n")) || !bytes.Contains(entry, []byte("SYSLOG_IDENTIFIER=test\n")) {
+		t.Errorf("unexpected entry %q...", entry[:64])
+	}
+}
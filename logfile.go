@@ -0,0 +1,107 @@
+package reopen
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// logFile is an opened log file together with the writes in flight on it.
+type logFile struct {
+	*os.File
+
+	baseSize int64        // size when opened
+	opened   time.Time    // time when opened
+	written  atomic.Int64 // bytes written through this writer since opened
+	lines    atomic.Int64 // newlines written through this writer since opened
+
+	logical   int64    // size without the padding of WithDirectIOAlignment, guarded by the writer's alignMu
+	tail      []byte   // last partial block written with WithDirectIOAlignment
+	successor *logFile // file reopened at the same inode, which continues the aligned writes
+
+	enc    *encryptor // set by WithEncryption
+	shards []*os.File // other descriptors of the file, see WithShardedWrites
+
+	truncationReported atomic.Bool
+
+	idleStat os.FileInfo // stat of f when goIdle closed it, so finishActive can still identify it
+
+	mu       sync.RWMutex
+	retired  bool
+	inflight sync.WaitGroup
+
+	closeMu sync.Mutex
+	closed  bool
+	onClose []func() // run once f is closed, see whenClosed
+}
+
+// whenClosed runs fn once f has been closed by closeFile, right away if it already is.
+func (f *logFile) whenClosed(fn func()) {
+	f.closeMu.Lock()
+	if !f.closed {
+		f.onClose = append(f.onClose, fn)
+		f.closeMu.Unlock()
+		return
+	}
+	f.closeMu.Unlock()
+	fn()
+}
+
+// markClosed records that f is closed and returns the functions given to whenClosed.
+func (f *logFile) markClosed() []func() {
+	f.closeMu.Lock()
+	defer f.closeMu.Unlock()
+	f.closed = true
+	onClose := f.onClose
+	f.onClose = nil
+	return onClose
+}
+
+// acquire registers an in-flight operation on f, it reports false if f has been retired.
+func (f *logFile) acquire() bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if f.retired {
+		return false
+	}
+	f.inflight.Add(1)
+	return true
+}
+
+func (f *logFile) release() {
+	f.inflight.Done()
+}
+
+// retire stops f from accepting new operations and waits at most timeout for the in-flight ones to finish.
+// It reports whether all in-flight operations finished in time.
+func (f *logFile) retire(timeout time.Duration) bool {
+	f.mu.Lock()
+	f.retired = true
+	f.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		f.inflight.Wait()
+		close(drained)
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-drained:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// truncated reports whether f got smaller than what has been written to it, i.e. it was truncated by someone else.
+func (f *logFile) truncated() bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Size() < f.baseSize+f.written.Load()
+}
+
+// reportTruncation reports whether this is the first call for f, so a truncation triggers a single reopen.
+func (f *logFile) reportTruncation() bool {
+	return f.truncationReported.CompareAndSwap(false, true)
+}
@@ -0,0 +1,28 @@
+package reopen_test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/owarai/reopen/reopentest"
+)
+
+// TestMain fails the tests if a writer is left with its goroutines running, every test must close its writers,
+// e.g. with reopentest.MustClose.
+func TestMain(m *testing.M) {
+	code := m.Run()
+	// the goroutines get their labels once they run, and may still be returning after Close
+	time.Sleep(50 * time.Millisecond)
+	leaked := reopentest.LeakedGoroutines()
+	for deadline := time.Now().Add(time.Second); len(leaked) > 0 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		leaked = reopentest.LeakedGoroutines()
+	}
+	if len(leaked) > 0 && code == 0 {
+		fmt.Fprintln(os.Stderr, "leaked goroutines:", leaked)
+		code = 1
+	}
+	os.Exit(code)
+}
@@ -0,0 +1,51 @@
+package reopen
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+)
+
+const openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// OpenMetricsHandler returns a handler serving the writer's statistics in the OpenMetrics text format,
+// so they can be scraped by Prometheus without depending on its client library.
+func (ws *ReopenableWriteSyncer) OpenMetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", openMetricsContentType)
+		_, _ = w.Write(ws.openMetrics())
+	})
+}
+
+func (ws *ReopenableWriteSyncer) openMetrics() []byte {
+	s := ws.Stats()
+	var fileSize int64
+	if f := ws.acquireIfOpen(); f != nil {
+		if fi, err := f.Stat(); err == nil {
+			fileSize = fi.Size()
+		}
+		f.release()
+	}
+	var lastRotation float64
+	if !s.LastRotation.IsZero() {
+		lastRotation = float64(s.LastRotation.UnixNano()) / 1e9
+	}
+
+	var buf bytes.Buffer
+	counter := func(name, help string, v int64) {
+		fmt.Fprintf(&buf, "# TYPE %s counter\n# HELP %s %s\n%s_total %d\n", name, name, help, name, v)
+	}
+	gauge := func(name, unit, help string, v float64) {
+		fmt.Fprintf(&buf, "# TYPE %s gauge\n# UNIT %s %s\n# HELP %s %s\n%s %g\n", name, name, unit, name, help, name, v)
+	}
+	counter("reopen_writes", "Number of Write calls.", s.Writes)
+	counter("reopen_bytes_written", "Number of bytes accepted by Write.", s.LogicalBytesWritten)
+	counter("reopen_rotations", "Number of reopens of the log file.", s.Rotations)
+	counter("reopen_errors", "Number of failed writes.", s.WriteErrorCount)
+	gauge("reopen_file_size_bytes", "bytes", "Size of the current log file.", float64(fileSize))
+	gauge("reopen_last_rotation_timestamp_seconds", "seconds", "Unix time of the last reopen.", lastRotation)
+	gauge("reopen_file_age_seconds", "seconds", "Time since the current log file was opened.", ws.FileAgeSeconds())
+	gauge("reopen_pending_bytes", "bytes", "Bytes accepted by Write and not yet written to the log file.", float64(s.PendingBytes))
+	buf.WriteString("# EOF\n")
+	return buf.Bytes()
+}
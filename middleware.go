@@ -0,0 +1,131 @@
+package reopen
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"sync"
+	"time"
+)
+
+// Middleware wraps a WriteSyncer, e.g. to transform, filter or observe the payloads before they reach next.
+type Middleware func(next WriteSyncer) WriteSyncer
+
+// Chain returns ws wrapped by middlewares, the first middleware sees the payloads first.
+// The result can be given to zapcore.NewCore like ws itself.
+func Chain(ws *ReopenableWriteSyncer, middlewares ...Middleware) WriteSyncer {
+	var out WriteSyncer = ws
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		out = middlewares[i](out)
+	}
+	return out
+}
+
+// middlewareWriter is a WriteSyncer whose Write is given by a middleware and Sync forwarded to next.
+type middlewareWriter struct {
+	next  WriteSyncer
+	write func(p []byte) (int, error)
+}
+
+func (w *middlewareWriter) Write(p []byte) (int, error) {
+	return w.write(p)
+}
+
+func (w *middlewareWriter) Sync() error {
+	return w.next.Sync()
+}
+
+// RateLimitMiddleware lets through at most perSecond payloads per second on average with bursts of up to burst
+// payloads, the other payloads are dropped and reported as written.
+func RateLimitMiddleware(perSecond float64, burst int) Middleware {
+	return func(next WriteSyncer) WriteSyncer {
+		var mu sync.Mutex
+		tokens := float64(burst)
+		last := time.Now()
+		return &middlewareWriter{next: next, write: func(p []byte) (int, error) {
+			mu.Lock()
+			now := time.Now()
+			tokens += now.Sub(last).Seconds() * perSecond
+			if tokens > float64(burst) {
+				tokens = float64(burst)
+			}
+			last = now
+			allowed := tokens >= 1
+			if allowed {
+				tokens--
+			}
+			mu.Unlock()
+			if !allowed {
+				return len(p), nil
+			}
+			return next.Write(p)
+		}}
+	}
+}
+
+// CompressMiddleware writes every payload as a gzip member, the file is a valid gzip stream
+// which can be read by gunzip or gzip.Reader.
+func CompressMiddleware() Middleware {
+	return func(next WriteSyncer) WriteSyncer {
+		var mu sync.Mutex
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		return &middlewareWriter{next: next, write: func(p []byte) (int, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			buf.Reset()
+			zw.Reset(&buf)
+			if _, err := zw.Write(p); err != nil {
+				return 0, err
+			}
+			if err := zw.Close(); err != nil {
+				return 0, err
+			}
+			if _, err := next.Write(buf.Bytes()); err != nil {
+				return 0, err
+			}
+			return len(p), nil
+		}}
+	}
+}
+
+// FilterMiddleware lets through the payloads for which keep returns true, the others are reported as written.
+func FilterMiddleware(keep func(p []byte) bool) Middleware {
+	return func(next WriteSyncer) WriteSyncer {
+		return &middlewareWriter{next: next, write: func(p []byte) (int, error) {
+			if !keep(p) {
+				return len(p), nil
+			}
+			return next.Write(p)
+		}}
+	}
+}
+
+// AuditMiddleware copies every payload written successfully to audit, e.g. a second file kept apart from the logs.
+// A failure of audit is returned to the caller.
+func AuditMiddleware(audit io.Writer) Middleware {
+	return func(next WriteSyncer) WriteSyncer {
+		return &middlewareWriter{next: next, write: func(p []byte) (int, error) {
+			n, err := next.Write(p)
+			if err != nil {
+				return n, err
+			}
+			if _, err := audit.Write(p); err != nil {
+				return n, err
+			}
+			return n, nil
+		}}
+	}
+}
+
+// MetricsMiddleware calls observe after every write with its result and duration.
+func MetricsMiddleware(observe func(n int, d time.Duration, err error)) Middleware {
+	return func(next WriteSyncer) WriteSyncer {
+		return &middlewareWriter{next: next, write: func(p []byte) (int, error) {
+			start := time.Now()
+			n, err := next.Write(p)
+			observe(n, time.Since(start), err)
+			return n, err
+		}}
+	}
+}
@@ -0,0 +1,96 @@
+package reopen_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/owarai/reopen"
+)
+
+func TestMiddlewares(t *testing.T) {
+	lines := []string{"keep 1\n", "drop 2\n", "keep 3\n", "keep 4\n"}
+	keep := func(p []byte) bool { return bytes.HasPrefix(p, []byte("keep")) }
+	var audit bytes.Buffer
+	var observed int
+	tests := []struct {
+		name        string
+		middlewares []reopen.Middleware
+		decode      func(t *testing.T, b []byte) []byte
+		want        string
+		audit       string
+		observed    int
+	}{
+		{"no middleware", nil, nil, strings.Join(lines, ""), "", 0},
+		{"filter", []reopen.Middleware{reopen.FilterMiddleware(keep)}, nil, "keep 1\nkeep 3\nkeep 4\n", "", 0},
+		{"rate limit without refill", []reopen.Middleware{reopen.RateLimitMiddleware(0, 2)}, nil,
+			"keep 1\ndrop 2\n", "", 0},
+		{"filter before audit", []reopen.Middleware{reopen.FilterMiddleware(keep), reopen.AuditMiddleware(&audit)}, nil,
+			"keep 1\nkeep 3\nkeep 4\n", "keep 1\nkeep 3\nkeep 4\n", 0},
+		{"audit before filter", []reopen.Middleware{reopen.AuditMiddleware(&audit), reopen.FilterMiddleware(keep)}, nil,
+			"keep 1\nkeep 3\nkeep 4\n", strings.Join(lines, ""), 0},
+		{"compress", []reopen.Middleware{reopen.CompressMiddleware()}, gunzip, strings.Join(lines, ""), "", 0},
+		{"metrics", []reopen.Middleware{reopen.MetricsMiddleware(func(n int, _ time.Duration, err error) {
+			if err == nil && n > 0 {
+				observed++
+			}
+		})}, nil, strings.Join(lines, ""), "", len(lines)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audit.Reset()
+			observed = 0
+			path := filepath.Join(t.TempDir(), "app.log")
+			ws, err := reopen.NewWithOptions(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out := reopen.Chain(ws, tt.middlewares...)
+			for _, line := range lines {
+				if n, err := out.Write([]byte(line)); err != nil || n != len(line) {
+					t.Fatalf("Write returned %d, %v", n, err)
+				}
+			}
+			if err := out.Sync(); err != nil {
+				t.Errorf("Sync: %v", err)
+			}
+			if err := ws.Close(); err != nil {
+				t.Fatal(err)
+			}
+			b, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.decode != nil {
+				b = tt.decode(t, b)
+			}
+			if string(b) != tt.want {
+				t.Errorf("file holds %q, want %q", b, tt.want)
+			}
+			if audit.String() != tt.audit {
+				t.Errorf("audit holds %q, want %q", audit.String(), tt.audit)
+			}
+			if observed != tt.observed {
+				t.Errorf("observed %d writes, want %d", observed, tt.observed)
+			}
+		})
+	}
+}
+
+func gunzip(t *testing.T, b []byte) []byte {
+	t.Helper()
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return plain
+}
@@ -0,0 +1,28 @@
+package reopen
+
+import (
+	"path/filepath"
+	"syscall"
+)
+
+// magic numbers of network filesystems, see statfs(2).
+var networkFSMagic = map[uint32]bool{
+	0x6969:     true, // NFS_SUPER_MAGIC
+	0x517b:     true, // SMB_SUPER_MAGIC
+	0xff534d42: true, // CIFS_MAGIC_NUMBER
+	0xfe534d42: true, // SMB2_MAGIC_NUMBER
+	0x73757245: true, // CODA_SUPER_MAGIC
+	0x5346414f: true, // AFS_SUPER_MAGIC
+	0x00c36400: true, // CEPH_SUPER_MAGIC
+	0x01021997: true, // V9FS_MAGIC
+	0x0bd00bd0: true, // LUSTRE_SUPER_MAGIC
+}
+
+// isNetworkFS reports whether the directory containing file is on a network filesystem.
+func isNetworkFS(file string) (bool, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(filepath.Dir(file), &st); err != nil {
+		return false, err
+	}
+	return networkFSMagic[uint32(st.Type)], nil
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package reopen
+
+// isNetworkFS is only implemented on linux.
+func isNetworkFS(string) (bool, error) {
+	return false, nil
+}
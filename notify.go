@@ -0,0 +1,41 @@
+package reopen
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// rotateDatagram is the payload sent by WithUDPNotify.
+var rotateDatagram = []byte("R")
+
+// dialNotify opens the socket configured by WithUDPNotify, addr is "ip:port" or the path of a unix datagram socket.
+func (ws *ReopenableWriteSyncer) dialNotify() error {
+	network := "udp"
+	if strings.HasPrefix(ws.notifyAddr, "/") {
+		network = "unixgram"
+	}
+	conn, err := net.Dial(network, ws.notifyAddr)
+	if err != nil {
+		return fmt.Errorf("reopen: notify %s: %w", ws.notifyAddr, err)
+	}
+	ws.notifyConn = conn
+	return nil
+}
+
+// notify sends the rotation datagram, a failure is reported to the error handler and does not fail the rotation.
+func (ws *ReopenableWriteSyncer) notify() {
+	if ws.notifyConn == nil {
+		return
+	}
+	if _, err := ws.notifyConn.Write(rotateDatagram); err != nil {
+		ws.handleError(fmt.Errorf("reopen: notify %s: %w", ws.notifyAddr, err))
+	}
+}
+
+// closeNotify closes the socket configured by WithUDPNotify.
+func (ws *ReopenableWriteSyncer) closeNotify() {
+	if ws.notifyConn != nil {
+		_ = ws.notifyConn.Close()
+	}
+}
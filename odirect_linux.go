@@ -0,0 +1,6 @@
+package reopen
+
+import "syscall"
+
+// oDirect is the flag set by WithODirect.
+const oDirect = syscall.O_DIRECT
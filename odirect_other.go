@@ -0,0 +1,6 @@
+//go:build !linux
+
+package reopen
+
+// oDirect is the flag set by WithODirect, O_DIRECT is only supported on linux.
+const oDirect = 0
@@ -0,0 +1,725 @@
+package reopen
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Option configures a ReopenableWriteSyncer created by NewWithOptions.
+type Option func(ws *ReopenableWriteSyncer)
+
+const (
+	defaultFileMode             os.FileMode = 0644
+	defaultDrainTimeout                     = 10 * time.Second
+	defaultCloseDelay                       = 10 * time.Second
+	asyncSyncInterval                       = time.Second
+	walQueueSize                            = 1024
+	watcherRestartDelay                     = 100 * time.Millisecond
+	maxWatcherRestartDelay                  = 30 * time.Second
+	defaultMaxConsecutiveErrors             = 10
+	closeQueueSize                          = 64
+)
+
+// WithFileMode specify the file mode when open the file(default is 0644).
+func WithFileMode(mode os.FileMode) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.fileMode = mode
+	}
+}
+
+// WithSignals specify which signals need to be monitored by reopen mechanics(default is USR1).
+func WithSignals(sig ...os.Signal) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.signals = append(ws.signals, sig...)
+	}
+}
+
+// WithAsyncSync makes Sync non-blocking: it only marks a sync as pending and returns nil immediately,
+// a background goroutine performs the real fsync once per second.
+//
+// Data written before a Sync call may be lost if the machine crashes before the background fsync runs,
+// the loss window is bounded by the fsync interval(one second). Close always performs a synchronous fsync.
+func WithAsyncSync() Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.asyncSync = true
+	}
+}
+
+// WithEventStream makes the writer send a RotationEvent to ch on every reopen.
+// The send never blocks, events are dropped and counted in Stats().DroppedEvents if ch is full.
+func WithEventStream(ch chan<- RotationEvent) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.events = ch
+	}
+}
+
+// WithDrainTimeout bounds how long a file being closed waits for the writes in flight on it (default is 10s),
+// after a reopen and in Close. The file is closed when d elapses even if some write stalls.
+func WithDrainTimeout(d time.Duration) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.drainTimeout = d
+	}
+}
+
+// WithTruncateOnOpen truncates the file when the writer is created, so every process run starts with an empty file.
+// Reopens after rotation always append.
+func WithTruncateOnOpen() Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.truncateOnOpen = true
+	}
+}
+
+// WithRotationGuardInterval ignores the signals received within d after a reopen,
+// which prevents double rotation when several tools send the signal at the same time.
+// Explicit Reopen calls are never ignored.
+func WithRotationGuardInterval(d time.Duration) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.guardInterval = d
+	}
+}
+
+// WithJSONValidation checks every payload with json.Valid and writes the invalid ones to the quarantine writer
+// instead of the file, see WithQuarantineWriter. It is off by default because validation costs CPU on every write.
+func WithJSONValidation() Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.jsonValidation = true
+	}
+}
+
+// WithQuarantineWriter specify where the payloads rejected by validation are written(default is os.Stderr).
+func WithQuarantineWriter(w io.Writer) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.quarantine = w
+	}
+}
+
+// WithErrorHandler specify the function receiving errors which can not be returned to the caller,
+// such as failures in background goroutines and warnings(default drops them).
+func WithErrorHandler(fn func(error)) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.errorHandler = fn
+	}
+}
+
+// WithNFSAware detects whether the log file lives on a network filesystem such as NFS or CIFS on linux.
+// Inode numbers are not stable there, so WithFileWatcher and WithPeriodicValidation, which compare inodes,
+// are not started and ErrNetworkFilesystem is reported to the error handler if one of them was requested.
+// The check is skipped on other platforms.
+func WithNFSAware() Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.nfsAware = true
+	}
+}
+
+// WithTraceExtractor specify how WriteCtx finds the trace and span of a context,
+// e.g. from trace.SpanContextFromContext of OpenTelemetry(default reads the value set by ContextWithTrace).
+func WithTraceExtractor(fn func(ctx context.Context) (TraceContext, bool)) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.traceExtractor = fn
+	}
+}
+
+// WithJSONInjection makes WriteCtx add the trace and span ids as fields of the JSON payload
+// instead of prepending a binary header.
+func WithJSONInjection() Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.jsonInjection = true
+	}
+}
+
+// WithChmod sets mode on the file after every open, including the files recreated by logrotate.
+// Unlike the mode given to open the file, it is not masked by the umask. Failures are reported to the error handler.
+func WithChmod(mode os.FileMode) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.chmod = &mode
+	}
+}
+
+// WithTruncationDetector checks every interval whether the file got smaller than what has been written to it,
+// e.g. by logrotate copytruncate which sends no signal, and reopens it with TriggerTruncation.
+func WithTruncationDetector(interval time.Duration) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.truncationInterval = interval
+	}
+}
+
+// WithAfterClose registers fn to run as the last step of Close, once every goroutine has exited and every file
+// has been closed. finalPath is the path of the last active file. Callbacks run in the order they were registered,
+// a panic is recovered and reported to the error handler.
+func WithAfterClose(fn func(finalPath string)) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.afterClose = append(ws.afterClose, fn)
+	}
+}
+
+// WithWALMode writes and fsyncs every entry to the write-ahead log at walPath before Write returns,
+// then copies it to the log file in the background. The WAL is emptied once it reaches maxWALSize bytes
+// and everything in it is fsynced to the log file. On start the entries left in the WAL by a crash are
+// replayed to the log file, see ReplayWAL. Entries may be duplicated by a replay but never lost.
+//
+// This costs an fsync per write and is meant for audit logs.
+func WithWALMode(walPath string, maxWALSize int64) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.walPath = walPath
+		ws.walMaxSize = maxWALSize
+	}
+}
+
+// WithOpenRetry makes the writer try to open the file up to maxAttempts times when it is created, waiting delay
+// between attempts, e.g. while another container creates the log directory. The total wait is bounded by
+// (maxAttempts-1)*delay. A RetryEvent is reported to the error handler before every retry. The reopens are only
+// retried by WithOpenRetryPolicy, whose policy is used for the creation too when WithOpenRetry is not used.
+func WithOpenRetry(maxAttempts int, delay time.Duration) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.openRetry = &constantRetryPolicy{delay: delay, maxAttempts: maxAttempts}
+	}
+}
+
+// WithStateStream makes the writer send a StateTransition to ch on every state change.
+// Like WithEventStream the send never blocks, transitions are dropped and counted in Stats().DroppedEvents.
+func WithStateStream(ch chan<- StateTransition) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.stateStream = ch
+	}
+}
+
+// WithCloseStrategy specify when the previous file is closed after a reopen(default is TimeCloseStrategy(10s)).
+func WithCloseStrategy(strategy CloseStrategy) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.closeStrategy = strategy
+	}
+}
+
+// WithCopyTruncateDetection checks the file size after every write and reopens the file with TriggerTruncation
+// as soon as it is smaller than what has been written to it, which catches logrotate copytruncate without
+// any signal. It costs a stat per write, WithTruncationDetector is the cheaper periodic variant.
+func WithCopyTruncateDetection() Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.copyTruncateDetection = true
+	}
+}
+
+// WithRotationNamer specify how Rotate names the backups(default is TimestampNamer).
+func WithRotationNamer(namer RotationNamer) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.namer = namer
+	}
+}
+
+// WithMaxBackups makes Rotate keep at most n backups, the oldest are removed.
+func WithMaxBackups(n int) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.maxBackups = n
+	}
+}
+
+// WithMaxTotalSize makes Rotate remove the oldest backups once all backups together exceed size bytes.
+func WithMaxTotalSize(size int64) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.maxTotalSize = size
+	}
+}
+
+// WithPostRotateHook registers fn to run after every reopen, whatever triggered it. Hooks run in the order they
+// were registered, synchronously in the goroutine performing the reopen, and must not reopen the writer themselves.
+func WithPostRotateHook(fn func(ev RotationEvent)) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.postRotate = append(ws.postRotate, fn)
+	}
+}
+
+// WithAbsolutePath makes the file path absolute when the writer is created,
+// so reopens use the same file even if the working directory changes.
+func WithAbsolutePath() Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.absolutePath = true
+	}
+}
+
+// WithResolveSymlinks follows the symlinks of the file path when the writer is created,
+// reopens then use the resolved path.
+func WithResolveSymlinks() Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.resolveSymlinks = true
+	}
+}
+
+// WithPostRotateCommand runs cmd with args and the backup path as last argument after every Rotate,
+// like the postrotate script of logrotate, e.g. WithPostRotateCommand("gzip", "-9").
+// The command runs in the background once the close strategy closed the previous file, e.g. after 10s by default,
+// with its output discarded, it is killed after one minute unless
+// WithPostRotateCommandTimeout says otherwise, and Close waits for it. Reopens which do not know the backup path,
+// such as the ones triggered by signals, do not run it.
+func WithPostRotateCommand(cmd string, args ...string) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		timeout := defaultPostRotateCommandTimeout
+		if ws.postRotateCmd != nil {
+			timeout = ws.postRotateCmd.timeout
+		}
+		ws.postRotateCmd = &postRotateCommand{name: cmd, args: args, timeout: timeout}
+	}
+}
+
+// WithPostRotateCommandTimeout bounds the run time of the post rotate command(default is 60s).
+func WithPostRotateCommandTimeout(d time.Duration) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		if ws.postRotateCmd == nil {
+			ws.postRotateCmd = &postRotateCommand{}
+		}
+		ws.postRotateCmd.timeout = d
+	}
+}
+
+// WithMaxLineLength truncates the payloads longer than n bytes to their first n bytes followed by the truncation
+// marker, which protects the disk from a runaway write. The dropped bytes are counted in Stats().TruncatedBytes.
+func WithMaxLineLength(n int) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.maxLineLength = n
+	}
+}
+
+// WithTruncationMarker specify what WithMaxLineLength appends to a truncated payload(default is "[TRUNCATED]").
+func WithTruncationMarker(marker string) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.truncationMarker = marker
+	}
+}
+
+// WithNewlineNormalization replaces every from by to in the payloads before they are written.
+// Payloads without from are written as is, without any allocation.
+func WithNewlineNormalization(from, to string) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.newlineFrom = []byte(from)
+		ws.newlineTo = []byte(to)
+	}
+}
+
+// WithWindowsToUnixNewlines replaces the "\r\n" line endings by "\n".
+func WithWindowsToUnixNewlines() Option {
+	return WithNewlineNormalization("\r\n", "\n")
+}
+
+// WithSignalBufferSize specify the buffer size of the channel receiving the signals(default is 1).
+// os/signal drops the signals arriving while the buffer is full, a single buffered signal is enough to reopen,
+// a larger buffer only matters to observe how many signals were delivered.
+func WithSignalBufferSize(n int) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.sigBufferSize = n
+	}
+}
+
+// WithAtomicWriteSize splits the payloads larger than n bytes into writes of at most n bytes, e.g. PIPE_BUF,
+// so each write(2) stays atomic for the other processes appending to the same file. The chunks of a payload
+// are written while holding a lock, so the payloads of this process never interleave. Payloads up to n bytes
+// are written with a single write as usual.
+func WithAtomicWriteSize(n int) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.atomicWriteSize = n
+	}
+}
+
+// WithUDPNotify sends the datagram "R" to addr after every reopen, which makes log shippers such as Vector or
+// Fluent Bit re-read the file. addr is "ip:port" for UDP or the path of a unix datagram socket. The socket is
+// opened when the writer is created and closed by Close, a failed send is reported to the error handler.
+func WithUDPNotify(addr string) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.notifyAddr = addr
+	}
+}
+
+// WithDeduplication suppresses the payloads written more than maxCount times within window, comparing them byte
+// by byte, e.g. the same error logged by a reconnect loop. As soon as the window of a payload expires, the line
+// "previous message repeated N times" is written in place of the N suppressed copies, the summaries of several
+// payloads in the order of their first copy. Close writes the pending ones. At most 1024 distinct payloads are
+// tracked, the window of the oldest one ends early to make room for a new one.
+func WithDeduplication(window time.Duration, maxCount int) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.dedup = newDeduplicator(window, maxCount)
+	}
+}
+
+// WithActiveSuffix opens the file at its path followed by suffix, e.g. app.log.writing, and renames it to its path
+// on every reopen and in Close, so the tools picking up *.log never see a file being written. The file at the path
+// must have been processed before the next reopen, it is never replaced and the active file is reused instead.
+// Rotate renames the active file to the backup directly.
+func WithActiveSuffix(suffix string) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.activeSuffix = suffix
+	}
+}
+
+// WithRotateWhenAlone delays the reopens triggered by signals, Reopen and Rotate until no other process has the
+// file open, e.g. the reader of an append-only pipeline still processing it. The file descriptors of the other
+// processes are polled from /proc, so it only works on linux and for the processes this one may inspect.
+// Close stops the wait, the rotation then fails with os.ErrClosed.
+func WithRotateWhenAlone() Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.rotateAlone = true
+	}
+}
+
+// WithMaxRestarts specify how many times the signal watcher is restarted once it gave up(default is 0),
+// see WithMaxConsecutiveErrors. Restarts wait 100ms, doubling up to 30s, and register the signals again.
+// ErrWatcherDead is reported to the error handler when no restart is left.
+func WithMaxRestarts(n int) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.maxRestarts = n
+	}
+}
+
+// WithJSONLValidation checks that every payload is a JSON value followed by newlines, which keeps the file valid
+// JSONL when several encoders share the writer. An invalid payload is written to the quarantine writer and replaced
+// in the file by {"_invalid":true,"_raw":"<payload in base64>","ts":"<time in RFC 3339>"}.
+func WithJSONLValidation() Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.jsonlValidation = true
+	}
+}
+
+// WithMicroBatcher groups the concurrent writes into a single write to the file, which saves syscalls when many
+// goroutines log at the same time. A batch is written once maxDelay elapsed since its first write or once it holds
+// maxBytes bytes, every Write blocks until its batch is written.
+// With 100 goroutines on a single vCPU, BenchmarkMicroBatcher measured 0.36 write syscalls per Write with a maxDelay
+// of 1µs and 0.011 with 100µs, against 1 without the batcher, but each Write then waits for its batch: 1.4µs and
+// 10µs per Write instead of 1.1µs. Pick maxDelay from that trade-off on the target machine.
+func WithMicroBatcher(maxDelay time.Duration, maxBytes int) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.batcher = newMicroBatcher(ws, maxDelay, maxBytes)
+	}
+}
+
+// WithFieldFilter removes the top-level fields named fields from the JSON object payloads, e.g. "password" or
+// "token", so they never reach the disk. Payloads are decoded and encoded again with encoding/json, which sorts
+// their fields, the payloads which are not JSON objects are written as is.
+func WithFieldFilter(fields ...string) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		if ws.filteredFields == nil {
+			ws.filteredFields = make(map[string]bool, len(fields))
+		}
+		for _, field := range fields {
+			ws.filteredFields[field] = true
+		}
+	}
+}
+
+// WithFastFieldFilter is WithFieldFilter scanning the payloads without decoding them, which keeps the
+// order of the other fields. Keys spelled with escape sequences, e.g. "pass\u0077ord", are unescaped before
+// being compared.
+func WithFastFieldFilter(fields ...string) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		WithFieldFilter(fields...)(ws)
+		ws.fastFieldFilter = true
+	}
+}
+
+// WithCloexec opens the file with O_CLOEXEC so the child processes do not inherit it and keep it alive after a
+// reopen. The os package already sets it on linux, macOS and the BSDs, the option makes it explicit and also sets
+// FD_CLOEXEC with fcntl on the file given to NewFromFile, which may have been inherited without it.
+func WithCloexec() Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.cloexec = true
+	}
+}
+
+// WithHTTPRotationTrigger registers on mux at path a handler reopening the file on POST requests carrying
+// "Authorization: Bearer <token>", for the environments such as AWS Lambda where no signal can be sent.
+// It answers with the path of the new file, ?dry_run=true only reports the file which would be reopened.
+// The handler is registered once the writer is created, the creation fails if mux already has a handler at path
+// or if token is empty.
+func WithHTTPRotationTrigger(mux *http.ServeMux, path, token string) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.httpTrigger = &httpTrigger{mux: mux, path: path, token: token}
+	}
+}
+
+// WithBinaryFraming prepends headerFn(len(p)) to every payload p, for the consumers expecting length-prefixed
+// binary frames instead of lines, e.g. WithBinaryFraming(LengthPrefixFramer(4, binary.BigEndian).Header).
+func WithBinaryFraming(headerFn func(payloadLen int) []byte) Option {
+	return WithFramer(Framer{Header: headerFn})
+}
+
+// WithFramer frames every payload with f, e.g. WithFramer(NetstringFramer()). Frames are built after the other
+// transformations and written with a single write, so a Sync never sees a partial frame.
+func WithFramer(f Framer) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.framer = &f
+	}
+}
+
+// WithArchiver makes a archive the backup of every Rotate in the background, once the close strategy closed the
+// previous file so no write is lost, e.g. after 10s by default. A failed archive is retried twice
+// with an exponential backoff before the error is reported to the error handler, Close waits for the archives.
+// Reopens which do not know the backup path, such as the ones triggered by signals, archive nothing.
+func WithArchiver(a *Archiver) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.archiver = a
+	}
+}
+
+// WithOpenRetryPolicy makes the writer retry the failed opens as decided by policy, when it is created unless
+// WithOpenRetry is used and on every reopen, e.g. while a network filesystem returns EIO or ESTALE(default is
+// no retry). Writes keep going to the previous file while a reopen retries, and Close interrupts the wait.
+// A RetryEvent is reported to the error handler before every retry.
+func WithOpenRetryPolicy(policy RetryPolicy) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.retryPolicy = policy
+	}
+}
+
+// WithPeriodicValidation checks every interval that the current file is still the one at the file path, comparing
+// their inodes, and reopens the file with TriggerValidation when the path has been removed or points to another
+// file, e.g. after a tmpfs cleanup or a rotation which sent no signal. Symlinks in the path are followed.
+func WithPeriodicValidation(interval time.Duration) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.validationInterval = interval
+	}
+}
+
+// WithODirect opens the file with O_DIRECT on linux, which bypasses the page cache. The writes must then be
+// aligned to the block size of the filesystem, see WithDirectIOAlignment. It is ignored on other platforms.
+func WithODirect() Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.odirect = true
+	}
+}
+
+// WithDirectIOAlignment writes the payloads in blocks of blockSize bytes from a buffer aligned to blockSize,
+// as O_DIRECT requires, blockSize being the block size of the filesystem such as 512 or 4096. The last block
+// is padded with zeros and rewritten by the next write, the padding is truncated when the file is closed,
+// after a reopen or by Close. The file is then written at explicit offsets instead of with O_APPEND, so no other
+// process may append to it.
+func WithDirectIOAlignment(blockSize int) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.directAlignment = blockSize
+	}
+}
+
+// WithSyncCoalescing makes Sync non-blocking like WithAsyncSync, the pending sync is performed once maxDelay
+// elapsed or as soon as minBytes have been written since the last fsync, whichever comes first. This turns the
+// Sync after every entry of some pipelines into one fsync per batch. Close always performs a synchronous fsync.
+func WithSyncCoalescing(maxDelay time.Duration, minBytes int64) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.asyncSync = true
+		ws.syncInterval = maxDelay
+		ws.syncMinBytes = minBytes
+	}
+}
+
+// WithFileWatcher reopens the file with TriggerWatcher as soon as another file is created or moved to its path,
+// e.g. by logrotate without postrotate script. The directory of the file is watched with inotify on linux,
+// the path is polled every second on other platforms. The events arriving within 100ms are coalesced.
+// The watch follows the path to its new directory after Relocate or a WithPathProvider rotation.
+func WithFileWatcher() Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.fileWatcher = true
+	}
+}
+
+// WithShardedWrites opens the file shards times and spreads the concurrent writes over the descriptors, so the
+// goroutines of different cores do not contend on a single one. The descriptors are opened with O_APPEND, each
+// write stays atomic, and they are all reopened together on rotation.
+// BenchmarkShardedWrites measured 64 goroutines on a single vCPU reaching 73% of the throughput of plain
+// *os.File writes with 64 shards and 80% with 1: sharding only pays off once cores contend on one descriptor,
+// measure it on the target machine.
+func WithShardedWrites(shards int) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.shards = shards
+	}
+}
+
+// WithRotationJitter delays every reopen by a random duration up to maxJitter, whatever triggered it, which spreads
+// the reopens of a fleet rotated by the same cron job over time instead of hitting a shared NAS at once.
+// Writes keep going to the previous file in the meantime.
+func WithRotationJitter(maxJitter time.Duration) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.maxJitter = maxJitter
+	}
+}
+
+// WithFileSystem makes the writer open and rename the log files through fsys instead of the os package,
+// e.g. a reopentest.InMemoryFS in tests, FS reads through it if it is a DirFileSystem. The WAL and the backups
+// removed by WithMaxBackups are not affected.
+func WithFileSystem(fsys FileSystem) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.fileSystem = fsys
+	}
+}
+
+// WithMaxConsecutiveErrors specify after how many reopens failing in a row the signal watcher gives up(default is 10).
+// Every failure is reported to the error handler and the watcher waits 100ms before handling the next signal,
+// so a transient error such as EMFILE does not stop the rotation for good.
+func WithMaxConsecutiveErrors(n int) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.maxFailures = n
+	}
+}
+
+// WithInactivityClose closes the file once no write happened for d, which releases the descriptor of a file
+// written sporadically, the writer is then in StateIdle. The next write opens the file again, Sync and the pollers
+// such as WithTruncationDetector leave it closed.
+func WithInactivityClose(d time.Duration) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.idleTimeout = d
+	}
+}
+
+// WithDailyRotationAt calls Rotate every day at hour:minute in loc(default is time.Local),
+// e.g. WithDailyRotationAt(9, 0, tokyo), whenever the process started. Failures are reported to the error handler.
+func WithDailyRotationAt(hour, minute int, loc *time.Location) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		if loc == nil {
+			loc = time.Local
+		}
+		ws.daily = &dailyRotation{hour: hour, minute: minute, loc: loc}
+	}
+}
+
+// WithCloseWorkers specify how many goroutines close the previous files after the reopens(default is 1).
+// The files wait in a queue for a worker instead of getting a goroutine each, so rapid rotations do not spawn
+// goroutines, a file is closed synchronously by the reopen when the queue is full or n is 0.
+func WithCloseWorkers(n int) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.closeWorkers = n
+	}
+}
+
+// WithFileHeader writes header as the first bytes of every new file, before any log entry,
+// so the parsers can detect the format version. It is not written to a file which is not empty when opened.
+func WithFileHeader(header []byte) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.fileHeader = append([]byte(nil), header...)
+	}
+}
+
+// WithAppendToExisting specify whether a non-empty file may be appended to when opened(default is true).
+// When false, a non-empty file, e.g. left by a crash during a rotation, is renamed by the RotationNamer first,
+// so every file starts empty, with the header of WithFileHeader.
+func WithAppendToExisting(appendToExisting bool) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.noAppend = !appendToExisting
+	}
+}
+
+// WithSignalCheck makes the constructors send each reopen signal to the process and return ErrSignalBlocked
+// if it is not delivered within 100ms. The other writers of the process receiving these signals reopen their files.
+func WithSignalCheck() Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.signalCheck = true
+	}
+}
+
+// WithAtomicReplace makes every write replace the file instead of appending to it: the payload goes to a new
+// temporary file in tmpDir(default is the directory of the file when tmpDir is empty), which is then renamed to
+// the file path, so a file is never modified once written. tmpDir must be on the filesystem of the file.
+// The temporary file is synced before the rename and the directory after it, then the file is reopened.
+func WithAtomicReplace(tmpDir string) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.atomicReplace = true
+		ws.replaceDir = tmpDir
+	}
+}
+
+// WithClock specify the clock of the time-based features, e.g. the rotation times, the backup names or
+// the inactivity of WithInactivityClose(default is RealClock). Tests use reopentest.MockClock to advance
+// the time without waiting. The polling intervals and the retry delays always use the real time.
+func WithClock(c Clock) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.clock = c
+	}
+}
+
+// WithOrderedTimestamps buffers the writes for maxDelay and writes them sorted by the top-level JSON field tsField,
+// a number of seconds since the epoch or a RFC 3339 time, so the concurrent writers produce a time-ordered file on
+// a best-effort basis. Every Write blocks until its batch is written, a payload without the field stays after
+// the previous one.
+func WithOrderedTimestamps(tsField string, maxDelay time.Duration) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.orderer = newTimestampOrderer(ws, tsField, maxDelay)
+	}
+}
+
+// WithStaticPrefix prepends prefix, e.g. the service name, to every payload, in the same write(2),
+// so the grep patterns written for a legacy format keep working.
+func WithStaticPrefix(prefix []byte) Option {
+	prefix = append([]byte(nil), prefix...)
+	return func(ws *ReopenableWriteSyncer) {
+		ws.staticPrefix = prefix
+	}
+}
+
+// WithBufferPool takes the buffers holding the payloads with the prefix of WithStaticPrefix and the framing
+// of WithFramer from pool, which must hold *[]byte (default is a pool of 4096 bytes buffers when pool is nil),
+// instead of allocating one per write. A buffer goes back to the pool once written, see NewBufferPool.
+// BenchmarkTransformPath measures 0 allocations per write with the pool and 1 without.
+func WithBufferPool(pool *sync.Pool) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		if pool == nil {
+			pool = defaultBufferPool
+		}
+		ws.bufferPool = pool
+	}
+}
+
+// WithEncryption encrypts the files with AES-256-GCM, key must be 32 bytes long. Every file starts with a random
+// nonce and holds records of up to 64KB of plaintext, Sync and the close of a file write the pending plaintext
+// as a shorter record. Read the files with Decrypt, the offsets of the plaintext are not the ones of the file.
+func WithEncryption(key []byte) Option {
+	key = append([]byte(nil), key...)
+	return func(ws *ReopenableWriteSyncer) {
+		ws.encryptionKey = key
+	}
+}
+
+// WithLokiLabels makes every payload a JSON object {"labels":{...},"log":<payload>} for Grafana Loki, the payload
+// is embedded as is if it is JSON and as a string otherwise. Every new file starts with a {"labels":{...}} line,
+// after the header of WithFileHeader.
+func WithLokiLabels(labels map[string]string) Option {
+	encoded, _ := json.Marshal(labels) // a map of strings always encodes
+	return func(ws *ReopenableWriteSyncer) {
+		ws.lokiLabels = encoded
+	}
+}
+
+// WithTruncateOnStart is WithTruncateOnOpen, for the development environments which want a fresh file on each start.
+// The previous content is lost as soon as the writer is created, even if the process crashes right after.
+func WithTruncateOnStart() Option {
+	return WithTruncateOnOpen()
+}
+
+// WithSharedMemoryCoordination shares a rotation sequence number between the processes writing the same file,
+// e.g. the workers of a pre-fork server, in a shared memory segment named key(a path, or a name in /dev/shm).
+// Every reopen of a process increments it, and the other processes reopen their file on their next write,
+// so a rotation needs no signal to every process.
+func WithSharedMemoryCoordination(key string) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.sharedKey = key
+	}
+}
+
+// WithPathProvider computes the file path on every reopen with pp, e.g. from a config server, instead of always
+// reopening the path given to the constructor. The path is kept if the new file cannot be opened, and
+// Relocate ignores pp.
+func WithPathProvider(pp PathProvider) Option {
+	return func(ws *ReopenableWriteSyncer) {
+		ws.pathProvider = pp
+	}
+}
+
+// WithLevelSizeMap specify the size at which the file of each level rotates, e.g. a smaller size for the fast
+// growing debug logs, levels is typically a map[zapcore.Level]int64. It only applies to writers splitting their
+// files by level: ReopenableWriteSyncer writes every level to a single file, so its constructors return
+// ErrNotApplicable.
+func WithLevelSizeMap[L ~int8](levels map[L]int64) Option {
+	sizes := make(map[int8]int64, len(levels))
+	for l, size := range levels {
+		sizes[int8(l)] = size
+	}
+	return func(ws *ReopenableWriteSyncer) {
+		ws.levelSizes = sizes
+	}
+}
@@ -0,0 +1,130 @@
+package reopen
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+)
+
+// timestampOrderer buffers the writes and writes them sorted by a JSON field, see WithOrderedTimestamps.
+type timestampOrderer struct {
+	ws       *ReopenableWriteSyncer
+	field    string
+	maxDelay time.Duration
+
+	mu      sync.Mutex
+	cur     *orderedBatch
+	flushMu sync.Mutex // held while a batch is written, taken with mu held to keep the batches in order
+}
+
+// orderedBatch is a group of writes sorted and flushed together.
+type orderedBatch struct {
+	entries []orderedEntry
+	done    chan struct{}
+	written int
+	err     error
+}
+
+type orderedEntry struct {
+	p   []byte
+	ts  float64 // seconds since the epoch
+	end int     // offset of the end of the entry in the sorted batch, set when sorted
+}
+
+func newTimestampOrderer(ws *ReopenableWriteSyncer, field string, maxDelay time.Duration) *timestampOrderer {
+	return &timestampOrderer{ws: ws, field: field, maxDelay: maxDelay}
+}
+
+// write adds p to the current batch and waits until the batch is written.
+func (o *timestampOrderer) write(p []byte) (int, error) {
+	o.mu.Lock()
+	b := o.cur
+	if b == nil {
+		b = &orderedBatch{done: make(chan struct{})}
+		o.cur = b
+		o.ws.clock.AfterFunc(o.maxDelay, func() { o.flushBatch(b) })
+	}
+	i := len(b.entries)
+	ts, ok := o.timestamp(p)
+	if !ok && i > 0 {
+		ts = b.entries[i-1].ts // keeps its place after the previous write
+	}
+	b.entries = append(b.entries, orderedEntry{p: p, ts: ts})
+	o.ws.pendingBytes.Add(int64(len(p)))
+	o.mu.Unlock()
+
+	<-b.done
+	if b.entries[i].end <= b.written {
+		return len(p), nil
+	}
+	return 0, b.err
+}
+
+// timestamp returns the value of the field in p, a number of seconds or a RFC 3339 time.
+func (o *timestampOrderer) timestamp(p []byte) (float64, bool) {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(p, &fields) != nil {
+		return 0, false
+	}
+	raw, ok := fields[o.field]
+	if !ok {
+		return 0, false
+	}
+	var seconds float64
+	if json.Unmarshal(raw, &seconds) == nil {
+		return seconds, true
+	}
+	var s string
+	if json.Unmarshal(raw, &s) != nil {
+		return 0, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return 0, false
+	}
+	return float64(t.UnixNano()) / 1e9, true
+}
+
+// flushBatch writes b unless it has already been written.
+func (o *timestampOrderer) flushBatch(b *orderedBatch) {
+	o.mu.Lock()
+	if o.cur != b {
+		o.mu.Unlock()
+		return
+	}
+	o.flushLocked()
+}
+
+// flush writes the current batch if any.
+func (o *timestampOrderer) flush() {
+	o.mu.Lock()
+	if o.cur == nil {
+		o.mu.Unlock()
+		return
+	}
+	o.flushLocked()
+}
+
+// flushLocked sorts and writes the current batch, it is called with mu held and releases it.
+func (o *timestampOrderer) flushLocked() {
+	b := o.cur
+	o.cur = nil
+	o.flushMu.Lock()
+	o.mu.Unlock()
+
+	sorted := make([]*orderedEntry, len(b.entries))
+	for i := range b.entries {
+		sorted[i] = &b.entries[i]
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].ts < sorted[j].ts })
+	var buf []byte
+	for _, e := range sorted {
+		buf = append(buf, e.p...)
+		e.end = len(buf)
+	}
+	b.written, b.err = o.ws.writeFile(buf)
+	o.ws.pendingBytes.Add(-int64(len(buf)))
+	o.flushMu.Unlock()
+	close(b.done)
+}
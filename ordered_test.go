@@ -0,0 +1,100 @@
+package reopen_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/owarai/reopen"
+	"github.com/owarai/reopen/reopentest"
+)
+
+func TestOrderedTimestamps(t *testing.T) {
+	tests := []struct {
+		name     string
+		payloads []string // in the order of the writes
+		want     []string // in the order of the file
+	}{
+		{"seconds", []string{`{"ts":3}`, `{"ts":1}`, `{"ts":2.5}`}, []string{`{"ts":1}`, `{"ts":2.5}`, `{"ts":3}`}},
+		{"RFC 3339 times",
+			[]string{`{"ts":"2021-03-14T12:00:02Z"}`, `{"ts":"2021-03-14T13:00:01+01:00"}`},
+			[]string{`{"ts":"2021-03-14T13:00:01+01:00"}`, `{"ts":"2021-03-14T12:00:02Z"}`}},
+		{"equal timestamps keep their order", []string{`{"ts":1,"n":1}`, `{"ts":1,"n":2}`},
+			[]string{`{"ts":1,"n":1}`, `{"ts":1,"n":2}`}},
+		{"payloads without the field stay after the previous one",
+			[]string{`{"ts":2}`, `not json`, `{"ts":1}`, `{"other":0}`},
+			[]string{`{"ts":1}`, `{"other":0}`, `{"ts":2}`, `not json`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log")
+			clock := reopentest.NewMockClock(time.Now())
+			ws, err := reopen.NewWithOptions(path, reopen.WithClock(clock),
+				reopen.WithOrderedTimestamps("ts", time.Second))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ws.Close()
+			var wg sync.WaitGroup
+			var pending int64
+			for _, p := range tt.payloads {
+				line := p + "\n"
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if n, err := ws.Write([]byte(line)); err != nil || n != len(line) {
+						t.Errorf("Write returned %d, %v", n, err)
+					}
+				}()
+				// one write at a time joins the batch, so their order is the order of the payloads
+				pending += int64(len(line))
+				for deadline := time.Now().Add(5 * time.Second); ws.PendingBytes() != pending; time.Sleep(time.Millisecond) {
+					if time.Now().After(deadline) {
+						t.Fatalf("%d bytes pending, want %d", ws.PendingBytes(), pending)
+					}
+				}
+			}
+			clock.Advance(time.Second)
+			wg.Wait()
+			b, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(tt.want, "\n") + "\n"; string(b) != got {
+				t.Errorf("file holds\n%s\nwant\n%s", b, got)
+			}
+		})
+	}
+}
+
+func TestOrderedTimestampsFlushedByClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	// the clock never advances, only Close flushes the batch
+	ws, err := reopen.NewWithOptions(path, reopen.WithClock(reopentest.NewMockClock(time.Now())),
+		reopen.WithOrderedTimestamps("ts", time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	written := make(chan error, 1)
+	go func() {
+		_, err := ws.Write([]byte(`{"ts":1}` + "\n"))
+		written <- err
+	}()
+	for deadline := time.Now().Add(5 * time.Second); ws.PendingBytes() != 9; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d bytes pending, want 9", ws.PendingBytes())
+		}
+	}
+	if err := ws.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-written; err != nil {
+		t.Errorf("Write flushed by Close returned %v", err)
+	}
+	if b, err := os.ReadFile(path); err != nil || string(b) != `{"ts":1}`+"\n" {
+		t.Errorf("file holds %q, %v", b, err)
+	}
+}
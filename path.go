@@ -0,0 +1,42 @@
+package reopen
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// canonicalize rewrites the file path as configured by WithAbsolutePath and WithResolveSymlinks.
+func (ws *ReopenableWriteSyncer) canonicalize() error {
+	if ws.absolutePath {
+		abs, err := filepath.Abs(ws.filePath)
+		if err != nil {
+			return err
+		}
+		ws.filePath = abs
+	}
+	if ws.resolveSymlinks {
+		resolved, err := resolveSymlinks(ws.filePath)
+		if err != nil {
+			return err
+		}
+		ws.filePath = resolved
+	}
+	return nil
+}
+
+// resolveSymlinks follows the symlinks of path, only its directory is resolved if the file does not exist yet.
+func resolveSymlinks(path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err == nil {
+		return resolved, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	dir, err := filepath.EvalSymlinks(filepath.Dir(path))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, filepath.Base(path)), nil
+}
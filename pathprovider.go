@@ -0,0 +1,60 @@
+package reopen
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// PathProvider computes the file path on every reopen, see WithPathProvider.
+type PathProvider interface {
+	// NextPath returns the path of the file opened by the seq-th reopen, current is the path of the current file.
+	NextPath(current string, seq int) string
+}
+
+type staticPathProvider string
+
+func (p staticPathProvider) NextPath(string, int) string {
+	return string(p)
+}
+
+// StaticPathProvider returns a PathProvider which always returns path.
+func StaticPathProvider(path string) PathProvider {
+	return staticPathProvider(path)
+}
+
+// clockPathProvider is implemented by the PathProviders depending on the time, the writer then gives them
+// the time of its clock, see WithClock.
+type clockPathProvider interface {
+	nextPathAt(current string, seq int, now time.Time) string
+}
+
+type templatePathProvider string
+
+func (p templatePathProvider) NextPath(current string, seq int) string {
+	return p.nextPathAt(current, seq, time.Now())
+}
+
+func (p templatePathProvider) nextPathAt(current string, seq int, now time.Time) string {
+	return os.Expand(string(p), func(name string) string {
+		switch name {
+		case "seq":
+			return strconv.Itoa(seq)
+		case "date":
+			return now.Format("2006-01-02")
+		case "pid":
+			return strconv.Itoa(os.Getpid())
+		case "current":
+			return current
+		default:
+			return os.Getenv(name)
+		}
+	})
+}
+
+// TemplatePathProvider returns a PathProvider which expands ${seq}, ${date}(2006-01-02), ${pid} and ${current}
+// in tmpl, any other ${NAME} or $NAME is replaced by the environment variable NAME when called,
+// e.g. "/var/log/app/${LOG_NAME}-${seq}.log". The writer takes ${date} from the clock of WithClock.
+func TemplatePathProvider(tmpl string) PathProvider {
+	return templatePathProvider(tmpl)
+}
@@ -0,0 +1,38 @@
+package reopen_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/owarai/reopen"
+	"github.com/owarai/reopen/reopentest"
+)
+
+func TestTemplatePathProviderUsesClock(t *testing.T) {
+	dir := t.TempDir()
+	clock := reopentest.NewMockClock(time.Date(2021, 3, 14, 12, 0, 0, 0, time.UTC))
+	ws, err := reopen.NewWithOptions(filepath.Join(dir, "app.log"), reopen.WithClock(clock),
+		reopen.WithPathProvider(reopen.TemplatePathProvider(filepath.Join(dir, "app-${date}-${seq}.log"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	tests := []struct {
+		advance time.Duration
+		want    string
+	}{
+		{0, "app-2021-03-14-1.log"},
+		{time.Hour, "app-2021-03-14-2.log"},
+		{24 * time.Hour, "app-2021-03-15-3.log"},
+	}
+	for _, tt := range tests {
+		clock.Advance(tt.advance)
+		if err := ws.Reopen(); err != nil {
+			t.Fatal(err)
+		}
+		if got := filepath.Base(ws.CurrentFilePath()); got != tt.want {
+			t.Errorf("reopened %s, want %s", got, tt.want)
+		}
+	}
+}
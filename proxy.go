@@ -0,0 +1,65 @@
+package reopen
+
+import (
+	"io"
+	"os"
+)
+
+// FileInterface is the part of *os.File used by the code which type-asserts its writer to *os.File,
+// it can assert to FileInterface instead to accept a FileProxy.
+type FileInterface interface {
+	io.WriteCloser
+	Fd() uintptr
+	Chmod(mode os.FileMode) error
+	Chown(uid, gid int) error
+	Stat() (os.FileInfo, error)
+	Name() string
+}
+
+// FileProxy implements FileInterface by delegating to the current file of a ReopenableWriteSyncer.
+// Like the writer, it follows the reopens: two calls may act on different files.
+type FileProxy struct {
+	*ReopenableWriteSyncer
+}
+
+var _ FileInterface = FileProxy{}
+
+// NewFileProxy returns a FileProxy of ws.
+func NewFileProxy(ws *ReopenableWriteSyncer) FileProxy {
+	return FileProxy{ws}
+}
+
+// Chmod changes the mode of the current file.
+func (p FileProxy) Chmod(mode os.FileMode) error {
+	f := p.acquire()
+	if f == nil {
+		return os.ErrClosed
+	}
+	defer f.release()
+	return f.Chmod(mode)
+}
+
+// Chown changes the owner of the current file.
+func (p FileProxy) Chown(uid, gid int) error {
+	f := p.acquire()
+	if f == nil {
+		return os.ErrClosed
+	}
+	defer f.release()
+	return f.Chown(uid, gid)
+}
+
+// Stat returns the FileInfo of the current file.
+func (p FileProxy) Stat() (os.FileInfo, error) {
+	f := p.acquire()
+	if f == nil {
+		return nil, os.ErrClosed
+	}
+	defer f.release()
+	return f.Stat()
+}
+
+// Name returns the path of the current file.
+func (p FileProxy) Name() string {
+	return p.CurrentFilePath()
+}
@@ -0,0 +1,70 @@
+package reopen_test
+
+import (
+	"errors"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/owarai/reopen"
+	"github.com/owarai/reopen/reopentest"
+)
+
+func newProxyLogger(t *testing.T, path string) (*log.Logger, *reopen.ReopenableWriteSyncer) {
+	t.Helper()
+	ws, err := reopen.NewWithOptions(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return log.New(reopen.NewFileProxy(ws), "", 0), ws
+}
+
+func assertFileContent(t *testing.T, path, want string) {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != want {
+		t.Errorf("file content %q, want %q", b, want)
+	}
+}
+
+func TestLogPanicThroughFileProxy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	logger, ws := newProxyLogger(t, path)
+	defer reopentest.MustClose(t, ws)
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("recovered %v, want boom", r)
+			}
+		}()
+		logger.Panic("boom")
+	}()
+	assertFileContent(t, path, "boom\n")
+}
+
+// TestLogFatalThroughFileProxy runs log.Fatal in a subprocess, which exits without closing the writer.
+func TestLogFatalThroughFileProxy(t *testing.T) {
+	if path := os.Getenv("REOPEN_FATAL_LOG"); path != "" {
+		logger, _ := newProxyLogger(t, path)
+		logger.Fatal("fatal error")
+		return
+	}
+	path := filepath.Join(t.TempDir(), "app.log")
+	cmd := exec.Command(os.Args[0], "-test.run=^TestLogFatalThroughFileProxy$")
+	cmd.Env = append(os.Environ(), "REOPEN_FATAL_LOG="+path)
+	out, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+		t.Fatalf("subprocess returned %v, want exit status 1:\n%s", err, out)
+	}
+	if strings.Contains(string(out), "fatal error") {
+		t.Errorf("log.Fatal wrote to the output instead of the file:\n%s", out)
+	}
+	assertFileContent(t, path, "fatal error\n")
+}
@@ -0,0 +1,89 @@
+package reopen
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+// ReadWriteSyncer is a ReopenableWriteSyncer which can also read back the current file.
+// Write always appends, Read and Seek use their own position which starts at 0 again after a reopen.
+type ReadWriteSyncer struct {
+	*ReopenableWriteSyncer
+
+	mu      sync.Mutex
+	readPos int64
+	readOf  *logFile // file readPos belongs to
+}
+
+// NewReadWriteSyncer create a ReadWriteSyncer for file, which is opened read-write.
+func NewReadWriteSyncer(file string, opts ...Option) (*ReadWriteSyncer, error) {
+	opts = append(opts, func(ws *ReopenableWriteSyncer) {
+		ws.openFlag = os.O_RDWR | os.O_APPEND | os.O_CREATE
+	})
+	ws, err := NewWithOptions(file, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &ReadWriteSyncer{ReopenableWriteSyncer: ws}, nil
+}
+
+// Read reads from the read position of the current file.
+func (rw *ReadWriteSyncer) Read(p []byte) (int, error) {
+	f := rw.acquire()
+	if f == nil {
+		return 0, os.ErrClosed
+	}
+	defer f.release()
+
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	rw.follow(f)
+	n, err := f.ReadAt(p, rw.readPos)
+	rw.readPos += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// Seek moves the read position, the write position is always the end of the file.
+func (rw *ReadWriteSyncer) Seek(offset int64, whence int) (int64, error) {
+	f := rw.acquire()
+	if f == nil {
+		return 0, os.ErrClosed
+	}
+	defer f.release()
+
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	rw.follow(f)
+	var base int64
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		base = rw.readPos
+	case io.SeekEnd:
+		fi, err := f.Stat()
+		if err != nil {
+			return 0, err
+		}
+		base = fi.Size()
+	default:
+		return 0, errors.New("reopen: invalid whence")
+	}
+	if base+offset < 0 {
+		return 0, errors.New("reopen: negative position")
+	}
+	rw.readPos = base + offset
+	return rw.readPos, nil
+}
+
+// follow resets the read position when f is not the file it belongs to, i.e. after a reopen.
+func (rw *ReadWriteSyncer) follow(f *logFile) {
+	if rw.readOf != f {
+		rw.readOf = f
+		rw.readPos = 0
+	}
+}
@@ -0,0 +1,84 @@
+package reopen_test
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/owarai/reopen"
+)
+
+func TestReadWriteSyncer(t *testing.T) {
+	rw, err := reopen.NewReadWriteSyncer(filepath.Join(t.TempDir(), "app.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rw.Close()
+	writeLines(t, rw.ReopenableWriteSyncer, "0123456789\n")
+
+	tests := []struct {
+		name    string
+		offset  int64
+		whence  int
+		pos     int64
+		read    string
+		wantErr bool
+	}{
+		{"start", 0, io.SeekStart, 0, "0123", false},
+		{"current", 2, io.SeekCurrent, 6, "6789", false},
+		{"end", -3, io.SeekEnd, 8, "89\n", false},
+		{"back from current", -11, io.SeekCurrent, 0, "0123", false},
+		{"negative position", -1, io.SeekStart, 0, "", true},
+		{"invalid whence", 0, 42, 0, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pos, err := rw.Seek(tt.offset, tt.whence)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Seek(%d, %d) succeeded at %d", tt.offset, tt.whence, pos)
+				}
+				return
+			}
+			if err != nil || pos != tt.pos {
+				t.Fatalf("Seek(%d, %d) returned %d, %v, want %d", tt.offset, tt.whence, pos, err, tt.pos)
+			}
+			buf := make([]byte, 4)
+			n, err := rw.Read(buf)
+			if err != nil || string(buf[:n]) != tt.read {
+				t.Errorf("Read returned %q, %v, want %q", buf[:n], err, tt.read)
+			}
+		})
+	}
+
+	// the writes append whatever the read position
+	if _, err := rw.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	writeLines(t, rw.ReopenableWriteSyncer, "tail\n")
+	if b, err := io.ReadAll(rw); err != nil || string(b) != "0123456789\ntail\n" {
+		t.Errorf("read %q, %v", b, err)
+	}
+}
+
+func TestReadWriteSyncerAfterReopen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	rw, err := reopen.NewReadWriteSyncer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rw.Close()
+	writeLines(t, rw.ReopenableWriteSyncer, "old file\n")
+	if _, err := io.ReadAll(rw); err != nil {
+		t.Fatal(err)
+	}
+	if err := rw.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	writeLines(t, rw.ReopenableWriteSyncer, "new file\n")
+	// the read position starts at 0 again in the new file
+	if b, err := io.ReadAll(rw); err != nil || string(b) != "new file\n" {
+		t.Errorf("read %q, %v after the rotation", b, err)
+	}
+}
@@ -0,0 +1,62 @@
+package reopengrpc
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// logEntry and logAck mirror the messages defined in log.proto.
+type logEntry struct {
+	service string
+	payload []byte
+}
+
+type logAck struct {
+	count uint64
+}
+
+// codec encodes the two messages of log.proto by hand, which keeps generated code out of the package.
+type codec struct{}
+
+func (codec) Name() string {
+	return "proto"
+}
+
+func (codec) Marshal(v interface{}) ([]byte, error) {
+	e, ok := v.(*logEntry)
+	if !ok {
+		return nil, fmt.Errorf("reopengrpc: unexpected message %T", v)
+	}
+	b := make([]byte, 0, len(e.service)+len(e.payload)+8)
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendString(b, e.service)
+	b = protowire.AppendTag(b, 2, protowire.BytesType)
+	b = protowire.AppendBytes(b, e.payload)
+	return b, nil
+}
+
+func (codec) Unmarshal(data []byte, v interface{}) error {
+	a, ok := v.(*logAck)
+	if !ok {
+		return fmt.Errorf("reopengrpc: unexpected message %T", v)
+	}
+	*a = logAck{}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+		if num == 1 && typ == protowire.VarintType {
+			a.count, n = protowire.ConsumeVarint(data)
+		} else {
+			n = protowire.ConsumeFieldValue(num, typ, data)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+	}
+	return nil
+}
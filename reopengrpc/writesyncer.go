@@ -0,0 +1,264 @@
+// Package reopengrpc implements a zapcore.WriteSyncer which streams log lines to a remote gRPC logging service.
+//
+// The service is described by log.proto in this directory. There is no file behind this WriteSyncer,
+// so unlike reopen.ReopenableWriteSyncer it does not monitor any signal.
+package reopengrpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+)
+
+var (
+	// ErrClosed is returned by Write and Sync after Close.
+	ErrClosed = errors.New("reopengrpc: write syncer closed")
+	// ErrSyncTimeout is returned by Sync when the service does not acknowledge the entries in time.
+	ErrSyncTimeout = errors.New("reopengrpc: sync timeout")
+)
+
+const streamMethod = "/reopen.v1.LogService/Stream"
+
+var streamDesc = grpc.StreamDesc{
+	StreamName:    "Stream",
+	ServerStreams: true,
+	ClientStreams: true,
+}
+
+// Option configures a WriteSyncer created by NewGRPCWriteSyncer.
+type Option func(ws *WriteSyncer)
+
+// WithBufferSize specify how many entries can be queued before Write blocks(default is 1024).
+func WithBufferSize(n int) Option {
+	return func(ws *WriteSyncer) {
+		ws.bufferSize = n
+	}
+}
+
+// WithBackoff specify the reconnect delay, which starts at base and doubles up to max(default is 100ms and 30s).
+func WithBackoff(base, max time.Duration) Option {
+	return func(ws *WriteSyncer) {
+		ws.backoffBase = base
+		ws.backoffMax = max
+	}
+}
+
+// WithSyncTimeout bounds how long Sync and Close wait for the acknowledgements(default is 5s).
+func WithSyncTimeout(d time.Duration) Option {
+	return func(ws *WriteSyncer) {
+		ws.syncTimeout = d
+	}
+}
+
+// WriteSyncer sends every Write as a LogEntry on a LogService.Stream call.
+// Entries not acknowledged when the stream breaks are sent again on the next stream.
+type WriteSyncer struct {
+	cc      *grpc.ClientConn
+	service string
+
+	bufferSize  int
+	backoffBase time.Duration
+	backoffMax  time.Duration
+	syncTimeout time.Duration
+
+	entries chan []byte
+	queued  atomic.Uint64
+
+	mu       sync.Mutex
+	inflight [][]byte      // sent but not yet acknowledged, oldest first
+	acked    uint64        // entries acknowledged in total
+	ackWait  chan struct{} // closed and replaced on every acknowledgement
+
+	closeOnce sync.Once
+	closing   chan struct{}
+	done      chan struct{}
+}
+
+// NewGRPCWriteSyncer create a WriteSyncer sending the log lines of serviceName over cc.
+// cc is owned by the caller and is not closed by Close.
+func NewGRPCWriteSyncer(cc *grpc.ClientConn, serviceName string, opts ...Option) (zapcore.WriteSyncer, error) {
+	if cc == nil {
+		return nil, errors.New("reopengrpc: nil client connection")
+	}
+	ws := &WriteSyncer{
+		cc:          cc,
+		service:     serviceName,
+		bufferSize:  1024,
+		backoffBase: 100 * time.Millisecond,
+		backoffMax:  30 * time.Second,
+		syncTimeout: 5 * time.Second,
+		ackWait:     make(chan struct{}),
+		closing:     make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(ws)
+	}
+	ws.entries = make(chan []byte, ws.bufferSize)
+	go ws.run()
+	return ws, nil
+}
+
+// Write queues a copy of p, it blocks while the buffer is full.
+func (ws *WriteSyncer) Write(p []byte) (int, error) {
+	b := make([]byte, len(p))
+	copy(b, p)
+	select {
+	case <-ws.closing:
+		return 0, ErrClosed
+	default:
+	}
+	select {
+	case <-ws.closing:
+		return 0, ErrClosed
+	case ws.entries <- b:
+		ws.queued.Add(1)
+		return len(p), nil
+	}
+}
+
+// Sync waits until every entry queued before the call is acknowledged by the service.
+func (ws *WriteSyncer) Sync() error {
+	target := ws.queued.Load()
+	timer := time.NewTimer(ws.syncTimeout)
+	defer timer.Stop()
+	for {
+		ws.mu.Lock()
+		acked, wait := ws.acked, ws.ackWait
+		ws.mu.Unlock()
+		if acked >= target {
+			return nil
+		}
+		select {
+		case <-wait:
+		case <-ws.done:
+			return ErrClosed
+		case <-timer.C:
+			return ErrSyncTimeout
+		}
+	}
+}
+
+// Close flushes the queued entries, bounded by the sync timeout, and stops streaming.
+func (ws *WriteSyncer) Close() error {
+	err := ws.Sync()
+	ws.closeOnce.Do(func() {
+		close(ws.closing)
+	})
+	<-ws.done
+	return err
+}
+
+func (ws *WriteSyncer) run() {
+	defer close(ws.done)
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && !ws.sleep(ws.backoff(attempt)) {
+			return
+		}
+		sent, err := ws.stream()
+		if err == nil {
+			return
+		}
+		if sent {
+			attempt = 0
+		}
+	}
+}
+
+// stream sends entries on one stream until it breaks or the writer is closed, which returns a nil error.
+// sent reports whether any entry was sent successfully.
+func (ws *WriteSyncer) stream() (sent bool, err error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := ws.cc.NewStream(ctx, &streamDesc, streamMethod, grpc.ForceCodec(codec{}))
+	if err != nil {
+		return false, err
+	}
+
+	broken := make(chan struct{})
+	go func() {
+		defer close(broken)
+		var last uint64
+		for {
+			var ack logAck
+			if err := stream.RecvMsg(&ack); err != nil {
+				return
+			}
+			if ack.count > last {
+				ws.ack(int(ack.count - last))
+				last = ack.count
+			}
+		}
+	}()
+	defer func() {
+		cancel()
+		<-broken
+	}()
+
+	ws.mu.Lock()
+	resend := append([][]byte(nil), ws.inflight...)
+	ws.mu.Unlock()
+	for _, b := range resend {
+		if err := stream.SendMsg(&logEntry{service: ws.service, payload: b}); err != nil {
+			return sent, err
+		}
+		sent = true
+	}
+	for {
+		select {
+		case <-ws.closing:
+			_ = stream.CloseSend()
+			return sent, nil
+		case <-broken:
+			return sent, errors.New("reopengrpc: stream broken")
+		case b := <-ws.entries:
+			ws.mu.Lock()
+			ws.inflight = append(ws.inflight, b)
+			ws.mu.Unlock()
+			if err := stream.SendMsg(&logEntry{service: ws.service, payload: b}); err != nil {
+				return sent, err
+			}
+			sent = true
+		}
+	}
+}
+
+func (ws *WriteSyncer) ack(n int) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if n > len(ws.inflight) {
+		n = len(ws.inflight)
+	}
+	ws.inflight = ws.inflight[n:]
+	ws.acked += uint64(n)
+	close(ws.ackWait)
+	ws.ackWait = make(chan struct{})
+}
+
+func (ws *WriteSyncer) backoff(attempt int) time.Duration {
+	d := ws.backoffBase
+	for i := 1; i < attempt && d < ws.backoffMax; i++ {
+		d *= 2
+	}
+	if d > ws.backoffMax {
+		d = ws.backoffMax
+	}
+	return d
+}
+
+// sleep waits for d and reports false if the writer is closed meanwhile.
+func (ws *WriteSyncer) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ws.closing:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,62 @@
+package reopentest
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MockClock is a reopen.Clock whose time only moves with Advance, so tests of the time-based features
+// are deterministic and do not wait. Its timers can be stopped, Reset uses the real time.
+type MockClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []mockTimer
+}
+
+type mockTimer struct {
+	at    time.Time
+	timer *time.Timer
+}
+
+// NewMockClock returns a MockClock set to now.
+func NewMockClock(now time.Time) *MockClock {
+	return &MockClock{now: now}
+}
+
+func (c *MockClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// AfterFunc returns a timer calling f once the clock has been advanced by d.
+func (c *MockClock) AfterFunc(d time.Duration, f func()) *time.Timer {
+	t := time.AfterFunc(math.MaxInt64, f)
+	if d <= 0 {
+		t.Reset(0)
+		return t
+	}
+	c.mu.Lock()
+	c.timers = append(c.timers, mockTimer{at: c.now.Add(d), timer: t})
+	c.mu.Unlock()
+	return t
+}
+
+// Advance moves the clock forward by d and fires the timers which expire.
+// Their functions run in their own goroutines, Advance does not wait for them.
+func (c *MockClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	sort.SliceStable(c.timers, func(i, j int) bool { return c.timers[i].at.Before(c.timers[j].at) })
+	i := 0
+	for ; i < len(c.timers) && !c.timers[i].at.After(c.now); i++ {
+		// a stopped timer is not fired
+		if c.timers[i].timer.Stop() {
+			c.timers[i].timer.Reset(0)
+		}
+	}
+	c.timers = append(c.timers[:0], c.timers[i:]...)
+}
@@ -0,0 +1,162 @@
+// Package reopentest provides test doubles for the users of package reopen.
+package reopentest
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"testing/fstest"
+
+	"github.com/owarai/reopen"
+)
+
+// InMemoryFS is a reopen.FileSystem keeping its files out of the real filesystem, so tests can check the
+// rotation logic without creating files at the paths they use. Every file is an anonymous temporary file,
+// removed from its directory as soon as it is created and released when the InMemoryFS is closed.
+// All the opens of a file share its offset and are opened for appending, like log files.
+type InMemoryFS struct {
+	mu    sync.Mutex
+	files map[string]*os.File
+}
+
+var _ reopen.DirFileSystem = (*InMemoryFS)(nil)
+
+// NewInMemoryFS returns an empty InMemoryFS.
+func NewInMemoryFS() *InMemoryFS {
+	return &InMemoryFS{files: make(map[string]*os.File)}
+}
+
+// OpenFile opens name, creating it if flag has os.O_CREATE and truncating it if flag has os.O_TRUNC.
+func (m *InMemoryFS) OpenFile(name string, flag int, _ os.FileMode) (*os.File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, ok := m.files[name]
+	switch {
+	case ok && flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrExist}
+	case !ok && flag&os.O_CREATE == 0:
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	case !ok:
+		var err error
+		if f, err = anonymousFile(); err != nil {
+			return nil, err
+		}
+		m.files[name] = f
+	}
+	if flag&os.O_TRUNC != 0 {
+		if err := f.Truncate(0); err != nil {
+			return nil, err
+		}
+	}
+	fd, err := syscall.Dup(int(f.Fd()))
+	if err != nil {
+		return nil, os.NewSyscallError("dup", err)
+	}
+	syscall.CloseOnExec(fd)
+	return os.NewFile(uintptr(fd), name), nil
+}
+
+// Rename moves oldpath to newpath, replacing the file at newpath if any.
+func (m *InMemoryFS) Rename(oldpath, newpath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, ok := m.files[oldpath]
+	if !ok {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: os.ErrNotExist}
+	}
+	if prev, ok := m.files[newpath]; ok {
+		_ = prev.Close()
+	}
+	delete(m.files, oldpath)
+	m.files[newpath] = f
+	return nil
+}
+
+// ReadFile returns the content of name.
+func (m *InMemoryFS) ReadFile(name string) ([]byte, error) {
+	m.mu.Lock()
+	f, ok := m.files[name]
+	m.mu.Unlock()
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, fi.Size())
+	if _, err := f.ReadAt(data, 0); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return data, nil
+}
+
+// DirFS returns a read-only fs.FS of the files in dir and its subdirectories, as they are when Open is called,
+// it is what ReopenableWriteSyncer.FS returns for a writer using m.
+func (m *InMemoryFS) DirFS(dir string) fs.FS {
+	return inMemoryDir{m: m, dir: filepath.Clean(dir)}
+}
+
+type inMemoryDir struct {
+	m   *InMemoryFS
+	dir string
+}
+
+func (d inMemoryDir) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	snapshot := fstest.MapFS{}
+	for _, path := range d.m.Files() {
+		rel, err := filepath.Rel(d.dir, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		data, err := d.m.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		snapshot[filepath.ToSlash(rel)] = &fstest.MapFile{Data: data, Mode: 0644}
+	}
+	return snapshot.Open(name)
+}
+
+// Files returns the names of the files, sorted.
+func (m *InMemoryFS) Files() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.files))
+	for name := range m.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Close releases every file, the descriptors returned by OpenFile stay valid until they are closed.
+func (m *InMemoryFS) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for name, f := range m.files {
+		_ = f.Close()
+		delete(m.files, name)
+	}
+	return nil
+}
+
+// anonymousFile creates a temporary file opened for appending and removes it from its directory.
+func anonymousFile() (*os.File, error) {
+	tmp, err := os.CreateTemp("", "reopentest-*")
+	if err != nil {
+		return nil, err
+	}
+	defer tmp.Close()
+	f, err := os.OpenFile(tmp.Name(), os.O_RDWR|os.O_APPEND, 0)
+	_ = os.Remove(tmp.Name())
+	return f, err
+}
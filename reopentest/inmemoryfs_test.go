@@ -0,0 +1,45 @@
+package reopentest_test
+
+import (
+	"io/fs"
+	"path/filepath"
+	"testing"
+
+	"github.com/owarai/reopen"
+	"github.com/owarai/reopen/reopentest"
+)
+
+func TestWriterFSReadsInMemoryFS(t *testing.T) {
+	mem := reopentest.NewInMemoryFS()
+	defer mem.Close()
+	dir := filepath.Join(t.TempDir(), "logs")
+	ws, err := reopen.NewWithOptions(filepath.Join(dir, "app.log"), reopen.WithFileSystem(mem))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopentest.MustClose(t, ws)
+	if _, err := ws.Write([]byte("hello\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := ws.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ws.Write([]byte("world\n")); err != nil {
+		t.Fatal(err)
+	}
+	fsys := ws.FS()
+	b, err := fs.ReadFile(fsys, "app.log")
+	if err != nil || string(b) != "world\n" {
+		t.Fatalf("app.log is %q, %v, want %q", b, err, "world\n")
+	}
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("%d files, want the active file and one backup", len(entries))
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "*")); len(matches) != 0 {
+		t.Errorf("files created on disk: %v", matches)
+	}
+}
@@ -0,0 +1,45 @@
+package reopentest
+
+import (
+	"bytes"
+	"runtime/pprof"
+	"strings"
+	"testing"
+
+	"github.com/owarai/reopen"
+)
+
+// MustClose closes ws and fails the test if Close returns an error, e.g. when ws was already closed,
+// so no writer is left with its goroutines running.
+func MustClose(t testing.TB, ws *reopen.ReopenableWriteSyncer) {
+	t.Helper()
+	if err := ws.Close(); err != nil {
+		t.Fatalf("close %s: %v", ws.CurrentFilePath(), err)
+	}
+}
+
+// LeakedGoroutines returns the labels of the goroutines started by the writers and still running, e.g.
+// {"path":"/tmp/app.log", "reopen":"watch"}, so a TestMain can check that every writer has been closed:
+//
+//	func TestMain(m *testing.M) {
+//		code := m.Run()
+//		if leaked := reopentest.LeakedGoroutines(); len(leaked) > 0 {
+//			fmt.Println("leaked goroutines:", leaked)
+//			code = 1
+//		}
+//		os.Exit(code)
+//	}
+func LeakedGoroutines() []string {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		return nil
+	}
+	var leaked []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		labels, ok := strings.CutPrefix(line, "# labels: ")
+		if ok && strings.Contains(labels, `"reopen":`) {
+			leaked = append(leaked, labels)
+		}
+	}
+	return leaked
+}
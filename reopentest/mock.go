@@ -0,0 +1,117 @@
+package reopentest
+
+import (
+	"bytes"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/owarai/reopen"
+)
+
+// mockEventBufferSize is the buffer of the channel returned by MockWriteSyncer.Events.
+const mockEventBufferSize = 16
+
+// MockWriteSyncer is an in-memory reopen.WriteSyncer whose Reopen simulates a rotation,
+// so tests can check what a component writes without any file.
+type MockWriteSyncer struct {
+	mu      sync.Mutex
+	written bytes.Buffer
+	current bytes.Buffer
+	rotated []string
+	syncs   int
+	closed  bool
+	events  chan reopen.RotationEvent
+}
+
+// NewMockWriteSyncer returns an empty MockWriteSyncer.
+func NewMockWriteSyncer() *MockWriteSyncer {
+	return &MockWriteSyncer{events: make(chan reopen.RotationEvent, mockEventBufferSize)}
+}
+
+func (m *MockWriteSyncer) Write(p []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return 0, os.ErrClosed
+	}
+	m.written.Write(p)
+	return m.current.Write(p)
+}
+
+func (m *MockWriteSyncer) Sync() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return os.ErrClosed
+	}
+	m.syncs++
+	return nil
+}
+
+func (m *MockWriteSyncer) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return os.ErrClosed
+	}
+	m.closed = true
+	return nil
+}
+
+// Reopen moves the content of the current file to the rotated files and sends a reopen.TriggerManual event,
+// which is dropped if the event buffer is full.
+func (m *MockWriteSyncer) Reopen() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return os.ErrClosed
+	}
+	m.rotated = append(m.rotated, m.current.String())
+	m.current.Reset()
+	select {
+	case m.events <- reopen.RotationEvent{Time: time.Now(), Trigger: reopen.TriggerManual}:
+	default:
+	}
+	return nil
+}
+
+// Written returns everything written, across the rotations.
+func (m *MockWriteSyncer) Written() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.written.String()
+}
+
+// Current returns what has been written since the last rotation.
+func (m *MockWriteSyncer) Current() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.current.String()
+}
+
+// Rotated returns the content of the rotated files, oldest first.
+func (m *MockWriteSyncer) Rotated() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.rotated...)
+}
+
+// Rotations returns the number of Reopen calls.
+func (m *MockWriteSyncer) Rotations() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.rotated)
+}
+
+// Syncs returns the number of Sync calls.
+func (m *MockWriteSyncer) Syncs() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.syncs
+}
+
+// Events returns the channel of the rotation events.
+func (m *MockWriteSyncer) Events() <-chan reopen.RotationEvent {
+	return m.events
+}
@@ -0,0 +1,49 @@
+package reopentest
+
+import (
+	"testing"
+	"time"
+)
+
+// TestWriter is a MockWriteSyncer with assertion helpers, closed when the test ends.
+type TestWriter struct {
+	*MockWriteSyncer
+}
+
+// NewTestWriter returns a TestWriter closed by the cleanup of t.
+func NewTestWriter(t testing.TB) *TestWriter {
+	tw := &TestWriter{MockWriteSyncer: NewMockWriteSyncer()}
+	t.Cleanup(func() { _ = tw.Close() })
+	return tw
+}
+
+// AssertWritten reports an error unless everything written, across the rotations, is want.
+func (tw *TestWriter) AssertWritten(t testing.TB, want string) {
+	t.Helper()
+	if got := tw.Written(); got != want {
+		t.Errorf("written %q, want %q", got, want)
+	}
+}
+
+// AssertRotationCount reports an error unless the writer has been rotated n times.
+func (tw *TestWriter) AssertRotationCount(t testing.TB, n int) {
+	t.Helper()
+	if got := tw.Rotations(); got != n {
+		t.Errorf("%d rotations, want %d", got, n)
+	}
+}
+
+// WaitForRotation waits on the rotation events until the writer has been rotated at least n times,
+// and fails the test if it takes longer than timeout.
+func (tw *TestWriter) WaitForRotation(t testing.TB, n int, timeout time.Duration) {
+	t.Helper()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for tw.Rotations() < n {
+		select {
+		case <-tw.Events():
+		case <-timer.C:
+			t.Fatalf("%d rotations after %s, want %d", tw.Rotations(), timeout, n)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package reopen
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// writeReplace writes p, after the header of WithFileHeader, to a new temporary file and renames it to the file path,
+// see WithAtomicReplace. The file is then reopened, so Sync and the other methods use the replaced file.
+// The replacements are serialized with the reopens by reloadMu.
+func (ws *ReopenableWriteSyncer) writeReplace(p []byte) (int, error) {
+	ws.reloadMu.Lock()
+	defer ws.reloadMu.Unlock()
+	if ws.closed() {
+		return 0, os.ErrClosed
+	}
+
+	dir := ws.replaceDir
+	if dir == "" {
+		dir = filepath.Dir(ws.filePath)
+	}
+	n, err := ws.replace(dir, p)
+	ws.physicalBytes.Add(int64(n))
+	if err != nil {
+		ws.writeErrors.Add(1)
+		return 0, err
+	}
+	if err := ws.reload(); err != nil && err != errReopenSuperseded {
+		ws.handleError(err)
+	}
+	return len(p), nil
+}
+
+// replace returns the number of bytes written to the temporary file, which is removed on error. The temporary file
+// is synced before the rename and the directory after it, so a crash leaves either the previous or the new content.
+func (ws *ReopenableWriteSyncer) replace(dir string, p []byte) (n int, err error) {
+	f, err := os.CreateTemp(dir, "."+filepath.Base(ws.filePath)+".*")
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+		}
+	}()
+	if len(ws.header) > 0 {
+		h, err := f.Write(ws.header)
+		n += h
+		if err != nil {
+			return n, err
+		}
+	}
+	w, err := f.Write(p)
+	n += w
+	if err != nil {
+		return n, err
+	}
+	if err = f.Chmod(ws.fileMode); err != nil {
+		return n, err
+	}
+	if err = f.Sync(); err != nil {
+		return n, err
+	}
+	if err = f.Close(); err != nil {
+		return n, err
+	}
+	if err = os.Rename(f.Name(), ws.activePath()); err != nil {
+		return n, err
+	}
+	return n, syncDir(filepath.Dir(ws.activePath()))
+}
+
+// syncDir fsyncs the directory dir, which makes the renames into it durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
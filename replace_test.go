@@ -0,0 +1,40 @@
+package reopen_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/owarai/reopen"
+)
+
+func TestAtomicReplace(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	ws, err := reopen.NewWithOptions(path, reopen.WithAtomicReplace(""), reopen.WithFileHeader([]byte("# state\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	for _, content := range []string{`{"n":1}`, `{"n":2}`, `{"n":3}`} {
+		if n, err := ws.Write([]byte(content)); err != nil || n != len(content) {
+			t.Fatalf("Write returned %d, %v", n, err)
+		}
+		if b, err := os.ReadFile(path); err != nil || string(b) != "# state\n"+content {
+			t.Errorf("file holds %q, %v", b, err)
+		}
+		if err := ws.Sync(); err != nil {
+			t.Errorf("Sync: %v", err)
+		}
+		cur, err := reopen.NewFileProxy(ws).Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi, err := os.Stat(path); err != nil || !os.SameFile(cur, fi) {
+			t.Errorf("the current file is not the replaced one: %v", err)
+		}
+	}
+	if entries, err := os.ReadDir(dir); err != nil || len(entries) != 1 {
+		t.Errorf("temporary files left: %v, %v", entries, err)
+	}
+}
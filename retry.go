@@ -0,0 +1,55 @@
+package reopen
+
+import "time"
+
+// RetryPolicy decides whether a failed open is retried, see WithOpenRetryPolicy.
+type RetryPolicy interface {
+	// NextDelay returns how long to wait before retrying after the failed attempt number attempt, starting at 1,
+	// and false if the open must not be retried.
+	NextDelay(attempt int, err error) (time.Duration, bool)
+}
+
+// constantRetryPolicy waits delay between maxAttempts attempts, see WithOpenRetry.
+type constantRetryPolicy struct {
+	delay       time.Duration
+	maxAttempts int
+}
+
+func (p constantRetryPolicy) NextDelay(attempt int, _ error) (time.Duration, bool) {
+	return p.delay, attempt < p.maxAttempts
+}
+
+type linearRetryPolicy struct {
+	base        time.Duration
+	maxAttempts int
+}
+
+// LinearRetryPolicy makes up to maxAttempts attempts, waiting base, 2*base, 3*base... between them.
+func LinearRetryPolicy(base time.Duration, maxAttempts int) RetryPolicy {
+	return linearRetryPolicy{base: base, maxAttempts: maxAttempts}
+}
+
+func (p linearRetryPolicy) NextDelay(attempt int, _ error) (time.Duration, bool) {
+	return time.Duration(attempt) * p.base, attempt < p.maxAttempts
+}
+
+type exponentialRetryPolicy struct {
+	base, cap   time.Duration
+	maxAttempts int
+}
+
+// ExponentialRetryPolicy makes up to maxAttempts attempts, waiting base, 2*base, 4*base... up to cap between them.
+func ExponentialRetryPolicy(base, cap time.Duration, maxAttempts int) RetryPolicy {
+	return exponentialRetryPolicy{base: base, cap: cap, maxAttempts: maxAttempts}
+}
+
+func (p exponentialRetryPolicy) NextDelay(attempt int, _ error) (time.Duration, bool) {
+	delay := p.base
+	for i := 1; i < attempt && delay < p.cap; i++ {
+		delay *= 2
+	}
+	if delay > p.cap {
+		delay = p.cap
+	}
+	return delay, attempt < p.maxAttempts
+}
@@ -0,0 +1,128 @@
+package reopen_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/owarai/reopen"
+)
+
+// flakyFS fails the opens for which fail returns true, given the number of the open starting at 1.
+type flakyFS struct {
+	fail func(n int) bool
+
+	mu    sync.Mutex
+	opens int
+}
+
+func (fs *flakyFS) OpenFile(name string, flag int, perm os.FileMode) (*os.File, error) {
+	fs.mu.Lock()
+	fs.opens++
+	n := fs.opens
+	fs.mu.Unlock()
+	if fs.fail(n) {
+		return nil, errors.New("open refused")
+	}
+	return os.OpenFile(name, flag, perm)
+}
+
+func (fs *flakyFS) Rename(oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+func TestOpenRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		fail      func(n int) bool
+		opts      []reopen.Option
+		createErr bool
+		reopenErr bool
+		retries   int
+	}{
+		{"creation retried", func(n int) bool { return n < 3 },
+			[]reopen.Option{reopen.WithOpenRetry(3, time.Millisecond)}, false, false, 2},
+		{"creation out of attempts", func(n int) bool { return n < 3 },
+			[]reopen.Option{reopen.WithOpenRetry(2, time.Millisecond)}, true, false, 1},
+		{"reopen not retried by WithOpenRetry", func(n int) bool { return n == 2 },
+			[]reopen.Option{reopen.WithOpenRetry(3, time.Millisecond)}, false, true, 0},
+		{"reopen retried by WithOpenRetryPolicy", func(n int) bool { return n == 2 },
+			[]reopen.Option{reopen.WithOpenRetryPolicy(reopen.LinearRetryPolicy(time.Millisecond, 2))}, false, false, 1},
+		{"creation uses WithOpenRetry over WithOpenRetryPolicy", func(n int) bool { return n < 3 },
+			[]reopen.Option{reopen.WithOpenRetry(3, time.Millisecond),
+				reopen.WithOpenRetryPolicy(reopen.LinearRetryPolicy(time.Millisecond, 1))}, false, false, 2},
+		{"reopen uses WithOpenRetryPolicy over WithOpenRetry", func(n int) bool { return n == 2 || n == 3 },
+			[]reopen.Option{reopen.WithOpenRetryPolicy(reopen.LinearRetryPolicy(time.Millisecond, 2)),
+				reopen.WithOpenRetry(5, time.Millisecond)}, false, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			retries := 0
+			opts := append([]reopen.Option{
+				reopen.WithFileSystem(&flakyFS{fail: tt.fail}),
+				reopen.WithErrorHandler(func(err error) {
+					var ev *reopen.RetryEvent
+					if errors.As(err, &ev) {
+						mu.Lock()
+						retries++
+						mu.Unlock()
+					}
+				}),
+			}, tt.opts...)
+			ws, err := reopen.NewWithOptions(filepath.Join(t.TempDir(), "app.log"), opts...)
+			if (err != nil) != tt.createErr {
+				t.Fatalf("creation returned %v", err)
+			}
+			if err == nil {
+				defer ws.Close()
+				if err := ws.Reopen(); (err != nil) != tt.reopenErr {
+					t.Errorf("Reopen returned %v", err)
+				}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if retries != tt.retries {
+				t.Errorf("%d retries, want %d", retries, tt.retries)
+			}
+		})
+	}
+}
+
+func TestCloseInterruptsReopenRetry(t *testing.T) {
+	retrying := make(chan struct{}, 1)
+	ws, err := reopen.NewWithOptions(filepath.Join(t.TempDir(), "app.log"),
+		reopen.WithFileSystem(&flakyFS{fail: func(n int) bool { return n > 1 }}),
+		reopen.WithOpenRetryPolicy(reopen.LinearRetryPolicy(time.Hour, 10)),
+		reopen.WithErrorHandler(func(err error) {
+			select {
+			case retrying <- struct{}{}:
+			default:
+			}
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reopened := make(chan error, 1)
+	go func() { reopened <- ws.Reopen() }()
+	<-retrying
+	if _, err := ws.Write([]byte("line\n")); err != nil {
+		t.Errorf("Write while a reopen retries: %v", err)
+	}
+	closed := make(chan error, 1)
+	go func() { closed <- ws.Close() }()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Errorf("Close returned %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close waited for the retry")
+	}
+	if err := <-reopened; err != os.ErrClosed {
+		t.Errorf("Reopen returned %v, want os.ErrClosed", err)
+	}
+}
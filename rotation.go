@@ -0,0 +1,126 @@
+package reopen
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrRotationInProgress is returned by Rotate when another Rotate call is running.
+var ErrRotationInProgress = errors.New("reopen: rotation in progress")
+
+// RotationNamer names the backups created by Rotate.
+type RotationNamer interface {
+	// BackupName returns the path the active file at path is renamed to by a rotation at t.
+	BackupName(path string, t time.Time) string
+	// IsBackup reports whether name, a file in the directory of path, is a backup returned by BackupName.
+	IsBackup(path, name string) bool
+}
+
+// backupTimeLayout is the suffix appended to the file path by TimestampNamer.
+const backupTimeLayout = "20060102T150405.000000000"
+
+// TimestampNamer names backups <path>.<time of rotation>, e.g. app.log.20220225T130405.000000000.
+type TimestampNamer struct{}
+
+func (TimestampNamer) BackupName(path string, t time.Time) string {
+	return path + "." + t.Format(backupTimeLayout)
+}
+
+func (TimestampNamer) IsBackup(path, name string) bool {
+	suffix := strings.TrimPrefix(name, path+".")
+	if suffix == name {
+		return false
+	}
+	_, err := time.Parse(backupTimeLayout, suffix)
+	return err == nil
+}
+
+// Rotate performs a complete rotation without logrotate: the active file is renamed to the name given by the
+// RotationNamer, a new file is opened at the file path, the post rotate hooks run, the backups beyond
+// WithMaxBackups and WithMaxTotalSize are removed, and the previous file is closed by the close strategy.
+// It returns ErrRotationInProgress if another Rotate call is running.
+func (ws *ReopenableWriteSyncer) Rotate() error {
+	if ws.noFile() {
+		return os.ErrClosed
+	}
+	if ws.stdio {
+		return nil
+	}
+	if !ws.rotating.CompareAndSwap(false, true) {
+		return ErrRotationInProgress
+	}
+	defer ws.rotating.Store(false)
+
+	ws.waitAlone()
+	ws.waitJitter()
+	ws.reloadMu.Lock()
+	if ws.closed() {
+		ws.reloadMu.Unlock()
+		return os.ErrClosed
+	}
+	backup := ws.namer.BackupName(ws.filePath, ws.clock.Now())
+	if err := ws.fileSystem.Rename(ws.activePath(), backup); err != nil {
+		ws.reloadMu.Unlock()
+		return err
+	}
+	err := ws.rotateLocked(TriggerRotate, nil, backup)
+	ws.reloadMu.Unlock()
+	if err != nil {
+		return err
+	}
+	return ws.removeOldBackups()
+}
+
+// removeOldBackups removes the oldest backups beyond the limits of WithMaxBackups and WithMaxTotalSize.
+func (ws *ReopenableWriteSyncer) removeOldBackups() error {
+	if ws.maxBackups <= 0 && ws.maxTotalSize <= 0 {
+		return nil
+	}
+	ws.reloadMu.Lock()
+	path := ws.filePath
+	ws.reloadMu.Unlock()
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	type backup struct {
+		name    string
+		size    int64
+		modTime time.Time
+	}
+	var backups []backup
+	for _, e := range entries {
+		name := filepath.Join(filepath.Dir(path), e.Name())
+		if e.IsDir() || !ws.namer.IsBackup(path, name) {
+			continue
+		}
+		fi, err := e.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{name: name, size: fi.Size(), modTime: fi.ModTime()})
+	}
+	// newest first
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].modTime.Equal(backups[j].modTime) {
+			return backups[i].modTime.After(backups[j].modTime)
+		}
+		return backups[i].name > backups[j].name
+	})
+	var total int64
+	var firstErr error
+	for i, b := range backups {
+		total += b.size
+		if (ws.maxBackups > 0 && i >= ws.maxBackups) || (ws.maxTotalSize > 0 && total > ws.maxTotalSize) {
+			if err := os.Remove(b.name); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,63 @@
+package reopen
+
+import (
+	"errors"
+	"sort"
+)
+
+// DefaultRoute is the key of the writer receiving the payloads whose key has no writer, see NewRoutingWriteSyncer.
+const DefaultRoute = ""
+
+// RoutingWriteSyncer writes every payload to one of several writers chosen from its content,
+// e.g. one file per tenant in a shared logging daemon.
+type RoutingWriteSyncer struct {
+	router func(p []byte) string
+	files  map[string]*ReopenableWriteSyncer
+	keys   []string // sorted, so Sync and Close handle the writers in a stable order
+}
+
+// NewRoutingWriteSyncer returns a RoutingWriteSyncer writing p to files[router(p)], or to files[DefaultRoute]
+// when router returns a key without writer. files must hold a DefaultRoute writer. Create the writers with
+// a RotationGroup so that a single signal registration reopens all of them.
+func NewRoutingWriteSyncer(router func(p []byte) string, files map[string]*ReopenableWriteSyncer) (*RoutingWriteSyncer, error) {
+	if files[DefaultRoute] == nil {
+		return nil, errors.New("reopen: routing writer needs a default writer")
+	}
+	r := &RoutingWriteSyncer{router: router, files: make(map[string]*ReopenableWriteSyncer, len(files))}
+	for key, ws := range files {
+		r.files[key] = ws
+		r.keys = append(r.keys, key)
+	}
+	sort.Strings(r.keys)
+	return r, nil
+}
+
+func (r *RoutingWriteSyncer) Write(p []byte) (int, error) {
+	ws, ok := r.files[r.router(p)]
+	if !ok {
+		ws = r.files[DefaultRoute]
+	}
+	return ws.Write(p)
+}
+
+// Sync syncs every writer and returns the first error encountered.
+func (r *RoutingWriteSyncer) Sync() error {
+	var firstErr error
+	for _, key := range r.keys {
+		if err := r.files[key].Sync(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Close closes every writer and returns the first error encountered.
+func (r *RoutingWriteSyncer) Close() error {
+	var firstErr error
+	for _, key := range r.keys {
+		if err := r.files[key].Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,76 @@
+package reopen_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/owarai/reopen"
+)
+
+func TestRoutingWriteSyncer(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		want   map[string]string // the content of each route's file
+	}{
+		{"routed by key", []string{"a:1\n", "b:2\n", "a:3\n"},
+			map[string]string{"a": "a:1\na:3\n", "b": "b:2\n", reopen.DefaultRoute: ""}},
+		{"unknown keys go to the default writer", []string{"c:1\n", "a:2\n"},
+			map[string]string{"a": "a:2\n", "b": "", reopen.DefaultRoute: "c:1\n"}},
+		{"payloads without key go to the default writer", []string{"no key\n"},
+			map[string]string{"a": "", "b": "", reopen.DefaultRoute: "no key\n"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			files := map[string]*reopen.ReopenableWriteSyncer{}
+			for key := range tt.want {
+				ws, err := reopen.NewWithOptions(filepath.Join(dir, "route-"+key+".log"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				files[key] = ws
+			}
+			r, err := reopen.NewRoutingWriteSyncer(func(p []byte) string {
+				key, _, _ := bytes.Cut(p, []byte(":"))
+				return string(key)
+			}, files)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, w := range tt.writes {
+				if n, err := r.Write([]byte(w)); err != nil || n != len(w) {
+					t.Fatalf("Write returned %d, %v", n, err)
+				}
+			}
+			if err := r.Sync(); err != nil {
+				t.Fatal(err)
+			}
+			if err := r.Close(); err != nil {
+				t.Fatal(err)
+			}
+			for key, want := range tt.want {
+				if _, err := files[key].Write([]byte("x")); err == nil {
+					t.Errorf("writer %q not closed", key)
+				}
+				if b, err := os.ReadFile(filepath.Join(dir, "route-"+key+".log")); err != nil || string(b) != want {
+					t.Errorf("route %q holds %q, %v, want %q", key, b, err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRoutingWriteSyncerNeedsDefault(t *testing.T) {
+	ws, err := reopen.NewWithOptions(filepath.Join(t.TempDir(), "app.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	if _, err := reopen.NewRoutingWriteSyncer(func([]byte) string { return "a" },
+		map[string]*reopen.ReopenableWriteSyncer{"a": ws}); err == nil {
+		t.Error("NewRoutingWriteSyncer accepted writers without default")
+	}
+}
@@ -0,0 +1,206 @@
+// Package s3 implements a zapcore.WriteSyncer which uploads the log lines as objects of an S3-compatible storage,
+// for the environments without a persistent filesystem.
+//
+// There is no file behind this WriteSyncer, so unlike reopen.ReopenableWriteSyncer it does not monitor any signal.
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxBufferSize = 8 << 20
+	defaultUploadTimeout = 30 * time.Second
+)
+
+// ErrClosed is returned by Write, Sync and Close after Close.
+var ErrClosed = errors.New("s3: write syncer closed")
+
+// S3Client is the part of an S3-compatible client used by S3WriteSyncer, e.g. a wrapper of the AWS SDK or of
+// presigned URLs.
+type S3Client interface {
+	PutObject(ctx context.Context, bucket, key string, body io.Reader) error
+}
+
+// Option configures an S3WriteSyncer created by NewS3WriteSyncer.
+type Option func(s *S3WriteSyncer)
+
+// WithMaxBufferSize specify how many bytes are buffered before they are uploaded as an object(default is 8MB).
+func WithMaxBufferSize(n int64) Option {
+	return func(s *S3WriteSyncer) {
+		s.maxSize = n
+	}
+}
+
+// WithFlushInterval uploads the buffered bytes every d, so the objects also rotate with time.
+func WithFlushInterval(d time.Duration) Option {
+	return func(s *S3WriteSyncer) {
+		s.interval = d
+	}
+}
+
+// WithUploadTimeout bounds every upload(default is 30s).
+func WithUploadTimeout(d time.Duration) Option {
+	return func(s *S3WriteSyncer) {
+		s.timeout = d
+	}
+}
+
+// WithErrorHandler specify the function receiving the errors of the uploads made in the background,
+// the uploads made by Sync and Close return their error instead.
+func WithErrorHandler(fn func(error)) Option {
+	return func(s *S3WriteSyncer) {
+		s.errorHandler = fn
+	}
+}
+
+// S3WriteSyncer buffers the writes in memory and uploads them as a new object <prefix>/<date>/<seq>.log
+// on Sync, on Close, every WithFlushInterval and in the background once the buffer is full, so Write never waits
+// for the network. The sequence number restarts at 0 every day and for every S3WriteSyncer, so the prefix must be
+// unique to the process, e.g. hold the host name and the pid. A failed upload keeps the bytes in the buffer for
+// the next one, the uploads are made one at a time so the objects keep the order of the writes.
+type S3WriteSyncer struct {
+	bucket       string
+	prefix       string
+	client       S3Client
+	maxSize      int64
+	interval     time.Duration
+	timeout      time.Duration
+	errorHandler func(error)
+
+	mu     sync.Mutex
+	buf    []byte
+	closed bool
+
+	uploadMu sync.Mutex // held during an upload
+	date     string
+	seq      int
+
+	full    chan struct{}
+	closing chan struct{}
+	done    chan struct{}
+}
+
+// NewS3WriteSyncer returns an S3WriteSyncer uploading to keyPrefix in bucket with client.
+func NewS3WriteSyncer(bucket, keyPrefix string, client S3Client, opts ...Option) (*S3WriteSyncer, error) {
+	if client == nil {
+		return nil, errors.New("s3: nil client")
+	}
+	s := &S3WriteSyncer{
+		bucket:  bucket,
+		prefix:  keyPrefix,
+		client:  client,
+		maxSize: defaultMaxBufferSize,
+		timeout: defaultUploadTimeout,
+		full:    make(chan struct{}, 1),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	go s.run()
+	return s, nil
+}
+
+// Write buffers a copy of p, the buffer is uploaded in the background once it holds the max buffer size.
+func (s *S3WriteSyncer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return 0, ErrClosed
+	}
+	s.buf = append(s.buf, p...)
+	full := int64(len(s.buf)) >= s.maxSize
+	s.mu.Unlock()
+	if full {
+		select {
+		case s.full <- struct{}{}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+// Sync uploads the buffered bytes as a new object.
+func (s *S3WriteSyncer) Sync() error {
+	s.mu.Lock()
+	closed := s.closed
+	s.mu.Unlock()
+	if closed {
+		return ErrClosed
+	}
+	return s.upload()
+}
+
+// Close stops the background uploads and uploads the buffered bytes.
+func (s *S3WriteSyncer) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrClosed
+	}
+	s.closed = true
+	s.mu.Unlock()
+	close(s.closing)
+	<-s.done
+	return s.upload()
+}
+
+// upload uploads the buffered bytes as the next object. The buffer is taken under mu, so the writes only wait
+// for the copy, and given back if the upload fails.
+func (s *S3WriteSyncer) upload() error {
+	s.uploadMu.Lock()
+	defer s.uploadMu.Unlock()
+	s.mu.Lock()
+	data := s.buf
+	s.buf = nil
+	s.mu.Unlock()
+	if len(data) == 0 {
+		return nil
+	}
+	if date := time.Now().UTC().Format("2006-01-02"); date != s.date {
+		s.date, s.seq = date, 0
+	}
+	key := path.Join(s.prefix, s.date, fmt.Sprintf("%06d.log", s.seq))
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	err := s.client.PutObject(ctx, s.bucket, key, bytes.NewReader(data))
+	cancel()
+	if err != nil {
+		s.mu.Lock()
+		s.buf = append(data, s.buf...)
+		s.mu.Unlock()
+		return fmt.Errorf("s3: upload %s: %w", key, err)
+	}
+	s.seq++
+	return nil
+}
+
+// run makes the uploads of a full buffer and of WithFlushInterval.
+func (s *S3WriteSyncer) run() {
+	defer close(s.done)
+	var tick <-chan time.Time
+	if s.interval > 0 {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-s.closing:
+			return
+		case <-s.full:
+		case <-tick:
+		}
+		if err := s.upload(); err != nil && s.errorHandler != nil {
+			s.errorHandler(err)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeClient struct {
+	mu      sync.Mutex
+	objects []string // key=body
+	fail    error
+	block   chan struct{} // PutObject waits for it or for the context when set
+}
+
+func (c *fakeClient) PutObject(ctx context.Context, bucket, key string, body io.Reader) error {
+	if c.block != nil {
+		select {
+		case <-c.block:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fail != nil {
+		return c.fail
+	}
+	c.objects = append(c.objects, key+"="+string(b))
+	return nil
+}
+
+func (c *fakeClient) uploaded() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.objects...)
+}
+
+func TestUploadsInOrder(t *testing.T) {
+	c := &fakeClient{}
+	s, err := NewS3WriteSyncer("bucket", "logs/host-1", c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	date := time.Now().UTC().Format("2006-01-02")
+	_, _ = s.Write([]byte("a\n"))
+	if err := s.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	c.mu.Lock()
+	c.fail = errors.New("unavailable")
+	c.mu.Unlock()
+	_, _ = s.Write([]byte("b\n"))
+	if err := s.Sync(); err == nil {
+		t.Fatal("failed upload not reported")
+	}
+	c.mu.Lock()
+	c.fail = nil
+	c.mu.Unlock()
+	_, _ = s.Write([]byte("c\n"))
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"logs/host-1/" + date + "/000000.log=a\n",
+		"logs/host-1/" + date + "/000001.log=b\nc\n",
+	}
+	if got := c.uploaded(); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("uploaded %q, want %q", got, want)
+	}
+	if _, err := s.Write([]byte("d\n")); err != ErrClosed {
+		t.Errorf("Write after Close returned %v", err)
+	}
+}
+
+func TestWriteDoesNotWaitForUpload(t *testing.T) {
+	c := &fakeClient{block: make(chan struct{})}
+	s, err := NewS3WriteSyncer("bucket", "logs", c, WithMaxBufferSize(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = s.Write([]byte("full\n")) // starts an upload which blocks
+	written := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			_, _ = s.Write([]byte("more\n"))
+		}
+		close(written)
+	}()
+	select {
+	case <-written:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Write waits for the upload")
+	}
+	close(c.block)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var total int
+	for _, o := range c.uploaded() {
+		total += strings.Count(o, "\n")
+	}
+	if total != 101 {
+		t.Errorf("%d lines uploaded, want 101", total)
+	}
+}
+
+func TestUploadTimeout(t *testing.T) {
+	c := &fakeClient{block: make(chan struct{})}
+	s, err := NewS3WriteSyncer("bucket", "logs", c, WithUploadTimeout(20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = s.Write([]byte("a\n"))
+	if err := s.Sync(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Sync returned %v, want a deadline exceeded error", err)
+	}
+	close(c.block)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.uploaded(); len(got) != 1 || !strings.HasSuffix(got[0], "=a\n") {
+		t.Errorf("uploaded %q, want the line kept after the timeout", got)
+	}
+}
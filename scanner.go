@@ -0,0 +1,141 @@
+package reopen
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// scannerPollInterval is how often a RotatingScanner waiting at the end of the file checks for more data.
+const scannerPollInterval = 100 * time.Millisecond
+
+// RotatingScanner reads the lines written to a ReopenableWriteSyncer and follows it across reopens.
+// It starts at the beginning of the current file and, after a reopen, continues with the new file once the
+// old one is read to its end.
+type RotatingScanner struct {
+	scanner *bufio.Scanner
+	r       *followReader
+}
+
+// Scanner returns a RotatingScanner over the file of ws, bufSize bounds the length of a line
+// (default is bufio.MaxScanTokenSize when bufSize <= 0).
+func (ws *ReopenableWriteSyncer) Scanner(bufSize int) (*RotatingScanner, error) {
+	r, err := newFollowReader(ws)
+	if err != nil {
+		return nil, err
+	}
+	if bufSize <= 0 {
+		bufSize = bufio.MaxScanTokenSize
+	}
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, bufSize), bufSize)
+	return &RotatingScanner{scanner: s, r: r}, nil
+}
+
+// Scan blocks until the next line is available and reports whether it got one,
+// it returns false once the scanner or the writer is closed, or on error.
+func (rs *RotatingScanner) Scan() bool {
+	return rs.scanner.Scan()
+}
+
+// Text returns the line read by the last Scan.
+func (rs *RotatingScanner) Text() string {
+	return rs.scanner.Text()
+}
+
+// Err returns the first error encountered by the scanner.
+func (rs *RotatingScanner) Err() error {
+	return rs.scanner.Err()
+}
+
+// Close stops following the file and unblocks Scan.
+func (rs *RotatingScanner) Close() error {
+	return rs.r.Close()
+}
+
+// followReader reads a file and waits for more data at its end instead of returning io.EOF,
+// it switches to the writer's new file after a reopen.
+type followReader struct {
+	ws      *ReopenableWriteSyncer
+	closing chan struct{}
+
+	mu   sync.Mutex
+	f    *os.File
+	once sync.Once
+}
+
+func newFollowReader(ws *ReopenableWriteSyncer) (*followReader, error) {
+	f, err := os.Open(ws.CurrentFilePath())
+	if err != nil {
+		return nil, err
+	}
+	return &followReader{ws: ws, f: f, closing: make(chan struct{})}, nil
+}
+
+func (r *followReader) Read(p []byte) (int, error) {
+	for {
+		r.mu.Lock()
+		if r.f == nil {
+			r.mu.Unlock()
+			return 0, io.EOF
+		}
+		n, err := r.f.Read(p)
+		if err == io.EOF {
+			err = r.switchFile()
+		}
+		r.mu.Unlock()
+		if n > 0 || (err != nil && err != io.EOF) {
+			return n, err
+		}
+		if err == nil {
+			// switched to the new file.
+			continue
+		}
+		if r.ws.closed() {
+			return 0, io.EOF
+		}
+		select {
+		case <-r.closing:
+			return 0, io.EOF
+		case <-time.After(scannerPollInterval):
+		}
+	}
+}
+
+// switchFile opens the writer's current file if it is not the one being read, it returns io.EOF otherwise.
+func (r *followReader) switchFile() error {
+	path := r.ws.CurrentFilePath()
+	fi, err := os.Stat(path)
+	if err != nil {
+		// the new file is not there yet.
+		return io.EOF
+	}
+	cur, err := r.f.Stat()
+	if err != nil {
+		return err
+	}
+	if os.SameFile(fi, cur) {
+		return io.EOF
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return io.EOF
+	}
+	_ = r.f.Close()
+	r.f = f
+	return nil
+}
+
+func (r *followReader) Close() error {
+	var err error
+	r.once.Do(func() {
+		close(r.closing)
+		r.mu.Lock()
+		err = r.f.Close()
+		r.f = nil
+		r.mu.Unlock()
+	})
+	return err
+}
@@ -0,0 +1,99 @@
+package reopen_test
+
+import (
+	"bufio"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/owarai/reopen"
+)
+
+func TestRotatingScanner(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(t *testing.T, ws *reopen.ReopenableWriteSyncer)
+		want  []string
+	}{
+		{"lines of the current file", func(t *testing.T, ws *reopen.ReopenableWriteSyncer) {
+			writeLines(t, ws, "one\n", "two\n")
+		}, []string{"one", "two"}},
+		{"across a Rotate", func(t *testing.T, ws *reopen.ReopenableWriteSyncer) {
+			writeLines(t, ws, "before\n")
+			if err := ws.Rotate(); err != nil {
+				t.Fatal(err)
+			}
+			writeLines(t, ws, "after\n")
+		}, []string{"before", "after"}},
+		{"across a Relocate", func(t *testing.T, ws *reopen.ReopenableWriteSyncer) {
+			writeLines(t, ws, "old path\n")
+			if err := ws.Relocate(filepath.Join(t.TempDir(), "other.log")); err != nil {
+				t.Fatal(err)
+			}
+			writeLines(t, ws, "new path\n")
+		}, []string{"old path", "new path"}},
+		{"a partial line completed later", func(t *testing.T, ws *reopen.ReopenableWriteSyncer) {
+			writeLines(t, ws, "par")
+			time.Sleep(150 * time.Millisecond) // the scanner waits at the end of the file
+			writeLines(t, ws, "tial\n")
+		}, []string{"partial"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ws, err := reopen.NewWithOptions(filepath.Join(t.TempDir(), "app.log"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ws.Close()
+			rs, err := ws.Scanner(0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rs.Close()
+			lines := make(chan string)
+			go func() {
+				defer close(lines)
+				for rs.Scan() {
+					lines <- rs.Text()
+				}
+			}()
+			tt.write(t, ws)
+			for _, want := range tt.want {
+				select {
+				case got := <-lines:
+					if got != want {
+						t.Errorf("scanned %q, want %q", got, want)
+					}
+				case <-time.After(5 * time.Second):
+					t.Fatalf("%q not scanned", want)
+				}
+			}
+			if err := rs.Close(); err != nil {
+				t.Fatal(err)
+			}
+			for range lines { // Close unblocks Scan
+			}
+		})
+	}
+}
+
+func TestRotatingScannerLineTooLong(t *testing.T) {
+	ws, err := reopen.NewWithOptions(filepath.Join(t.TempDir(), "app.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	writeLines(t, ws, strings.Repeat("x", 64)+"\n")
+	rs, err := ws.Scanner(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Close()
+	if rs.Scan() {
+		t.Fatalf("scanned %q longer than the buffer", rs.Text())
+	}
+	if rs.Err() != bufio.ErrTooLong {
+		t.Errorf("Err returned %v, want bufio.ErrTooLong", rs.Err())
+	}
+}
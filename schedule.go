@@ -0,0 +1,41 @@
+package reopen
+
+import (
+	"os"
+	"time"
+)
+
+// dailyRotation is the schedule of WithDailyRotationAt.
+type dailyRotation struct {
+	hour, minute int
+	loc          *time.Location
+}
+
+// next returns the first rotation time after now.
+func (d dailyRotation) next(now time.Time) time.Time {
+	local := now.In(d.loc)
+	t := time.Date(local.Year(), local.Month(), local.Day(), d.hour, d.minute, 0, 0, d.loc)
+	if !t.After(now) {
+		t = time.Date(local.Year(), local.Month(), local.Day()+1, d.hour, d.minute, 0, 0, d.loc)
+	}
+	return t
+}
+
+// rotateDaily calls Rotate at every time of the daily schedule, the next time is computed from the wall clock
+// after each rotation so the schedule never drifts.
+func (ws *ReopenableWriteSyncer) rotateDaily() {
+	for {
+		now := ws.clock.Now()
+		due := make(chan struct{})
+		timer := ws.clock.AfterFunc(ws.daily.next(now).Sub(now), func() { close(due) })
+		select {
+		case <-ws.closing:
+			timer.Stop()
+			return
+		case <-due:
+		}
+		if err := ws.Rotate(); err != nil && err != os.ErrClosed {
+			ws.handleError(err)
+		}
+	}
+}
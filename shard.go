@@ -0,0 +1,43 @@
+package reopen
+
+import (
+	"os"
+	"sync/atomic"
+)
+
+// openShards opens the shards-1 descriptors of WithShardedWrites in addition to the main one.
+func (ws *ReopenableWriteSyncer) openShards(name string, flag int) ([]*os.File, error) {
+	if ws.shards <= 1 {
+		return nil, nil
+	}
+	shards := make([]*os.File, 0, ws.shards-1)
+	for i := 1; i < ws.shards; i++ {
+		f, err := ws.fileSystem.OpenFile(name, flag, ws.fileMode)
+		if err != nil {
+			for _, shard := range shards {
+				_ = shard.Close()
+			}
+			return nil, err
+		}
+		shards = append(shards, f)
+	}
+	return shards, nil
+}
+
+// nextShardID numbers the shard ids handed out by shardIDs.
+var nextShardID atomic.Uint32
+
+// writeShard writes p to one of the descriptors of f. The shard ids come from a sync.Pool, whose per-P caches
+// keep giving a core the same descriptor, they are pointers so putting them back does not allocate.
+func (ws *ReopenableWriteSyncer) writeShard(f *logFile, p []byte) (int, error) {
+	id, ok := ws.shardIDs.Get().(*uint32)
+	if !ok {
+		id = new(uint32)
+		*id = nextShardID.Add(1)
+	}
+	defer ws.shardIDs.Put(id)
+	if i := int(*id % uint32(len(f.shards)+1)); i > 0 {
+		return f.shards[i-1].Write(p)
+	}
+	return f.Write(p)
+}
@@ -0,0 +1,55 @@
+package reopen_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/owarai/reopen"
+	"github.com/owarai/reopen/reopentest"
+)
+
+// BenchmarkShardedWrites compares 64 goroutines writing through WithShardedWrites to the peak of writing
+// to a plain *os.File with O_APPEND, the same syscall without the writer.
+func BenchmarkShardedWrites(b *testing.B) {
+	const goroutines = 64
+	b.Run("peak", func(b *testing.B) {
+		f, err := os.OpenFile(filepath.Join(b.TempDir(), "app.log"), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer f.Close()
+		b.SetBytes(int64(len(benchLine)))
+		b.ReportAllocs()
+		b.SetParallelism(goroutines)
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if _, err := f.Write(benchLine); err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		})
+	})
+	for _, shards := range []int{1, 8, 64} {
+		b.Run(fmt.Sprintf("shards-%d", shards), func(b *testing.B) {
+			ws, err := reopen.NewWithOptions(filepath.Join(b.TempDir(), "app.log"), reopen.WithShardedWrites(shards))
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer reopentest.MustClose(b, ws)
+			b.SetBytes(int64(len(benchLine)))
+			b.ReportAllocs()
+			b.SetParallelism(goroutines)
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := ws.Write(benchLine); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
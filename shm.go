@@ -0,0 +1,85 @@
+package reopen
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+)
+
+// sharedSeqSize is the size of the shared memory segment of WithSharedMemoryCoordination.
+const sharedSeqSize = 8
+
+// sharedSeq is a rotation sequence number shared by the processes writing the same file, in a memory mapped file.
+// Go has no System V shared memory call, a file mapped with MAP_SHARED in /dev/shm is the POSIX equivalent.
+type sharedSeq struct {
+	mem []byte
+	seq *uint64
+}
+
+// sharedSeqPath returns the path of the segment for key, a path or a name in /dev/shm or else the temp directory.
+func sharedSeqPath(key string) string {
+	if filepath.IsAbs(key) {
+		return key
+	}
+	dir := "/dev/shm"
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "reopen-"+key)
+}
+
+// openSharedSeq maps the segment at path, created with mode if needed.
+func openSharedSeq(path string, mode os.FileMode) (*sharedSeq, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, mode)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size() < sharedSeqSize {
+		if err := f.Truncate(sharedSeqSize); err != nil {
+			return nil, err
+		}
+	}
+	mem, err := syscall.Mmap(int(f.Fd()), 0, sharedSeqSize, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+	s := &sharedSeq{mem: mem, seq: (*uint64)(unsafe.Pointer(&mem[0]))}
+	// unmapped once the writer is unreachable, so a Write racing with Close never reads unmapped memory.
+	runtime.SetFinalizer(s, func(s *sharedSeq) { _ = syscall.Munmap(s.mem) })
+	return s, nil
+}
+
+func (s *sharedSeq) load() uint64 {
+	return atomic.LoadUint64(s.seq)
+}
+
+func (s *sharedSeq) increment() uint64 {
+	return atomic.AddUint64(s.seq, 1)
+}
+
+// followShared reopens the file when another process rotated it since the last reopen of this one,
+// see WithSharedMemoryCoordination.
+func (ws *ReopenableWriteSyncer) followShared() {
+	seq := ws.shared.load()
+	if seq == ws.localSeq.Load() {
+		return
+	}
+	ws.reloadMu.Lock()
+	defer ws.reloadMu.Unlock()
+	if ws.closed() || seq == ws.localSeq.Load() {
+		return
+	}
+	if err := ws.rotateLocked(TriggerCoordination, nil, ""); err != nil {
+		ws.handleError(err)
+		return
+	}
+	ws.localSeq.Store(seq)
+}
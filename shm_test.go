@@ -0,0 +1,69 @@
+package reopen_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/owarai/reopen"
+)
+
+func TestSharedMemoryCoordination(t *testing.T) {
+	tests := []struct {
+		name   string
+		rotate func(ws *reopen.ReopenableWriteSyncer) error
+	}{
+		{"Rotate", (*reopen.ReopenableWriteSyncer).Rotate},
+		{"Reopen after a rename", func(ws *reopen.ReopenableWriteSyncer) error {
+			if err := os.Rename(ws.CurrentFilePath(), ws.CurrentFilePath()+".1"); err != nil {
+				return err
+			}
+			return ws.Reopen()
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "app.log")
+			key := filepath.Join(dir, "seq") // the segment shared by the two writers, as by two processes
+			events := make(chan reopen.RotationEvent, 4)
+			a, err := reopen.NewWithOptions(path, reopen.WithSharedMemoryCoordination(key))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer a.Close()
+			b, err := reopen.NewWithOptions(path, reopen.WithSharedMemoryCoordination(key), reopen.WithEventStream(events))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer b.Close()
+
+			writeLines(t, b, "b before\n")
+			if err := tt.rotate(a); err != nil {
+				t.Fatal(err)
+			}
+			writeLines(t, b, "b after\n")
+			if got, err := os.ReadFile(path); err != nil || string(got) != "b after\n" {
+				t.Errorf("new file holds %q, %v", got, err)
+			}
+			select {
+			case ev := <-events:
+				if ev.Trigger != reopen.TriggerCoordination {
+					t.Errorf("b reopened with %s, want %s", ev.Trigger, reopen.TriggerCoordination)
+				}
+			default:
+				t.Error("b did not reopen")
+			}
+
+			// the reopen of b follows a, it does not make a reopen again
+			rotations := a.Stats().Rotations
+			writeLines(t, a, "a after\n")
+			if a.Stats().Rotations != rotations {
+				t.Error("a reopened after following itself")
+			}
+			if got, err := os.ReadFile(path); err != nil || string(got) != "b after\na after\n" {
+				t.Errorf("new file holds %q, %v", got, err)
+			}
+		})
+	}
+}
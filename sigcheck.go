@@ -0,0 +1,42 @@
+package reopen
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const signalCheckTimeout = 100 * time.Millisecond
+
+// checkSignals sends each reopen signal to the process and waits for its delivery, see WithSignalCheck.
+// It is called once the signals are relayed to reopenSig and before the watcher starts, so the test
+// signals do not reopen the file.
+func (ws *ReopenableWriteSyncer) checkSignals() error {
+	for _, sig := range ws.signals {
+		s, ok := sig.(syscall.Signal)
+		if !ok {
+			continue
+		}
+		delivered := make(chan os.Signal, 1)
+		signal.Notify(delivered, s)
+		err := syscall.Kill(os.Getpid(), s)
+		if err == nil {
+			select {
+			case <-delivered:
+			case <-time.After(signalCheckTimeout):
+				err = ErrSignalBlocked
+			}
+		}
+		signal.Stop(delivered)
+		select {
+		case <-ws.reopenSig:
+		default:
+		}
+		if err != nil {
+			return fmt.Errorf("reopen: check %s: %w", s, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+//go:build !windows
+
+package reopen_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/owarai/reopen"
+	"github.com/owarai/reopen/reopentest"
+)
+
+// TestSignalsDuringWrites sends reopen signals as fast as possible while goroutines write, run it with -race.
+// SIGWINCH is ignored by default, so a signal still in flight after Close does not kill the test.
+func TestSignalsDuringWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	ws, err := reopen.NewWithOptions(path, reopen.WithSignals(syscall.SIGWINCH))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const writers, rotations = 8, 20
+	var written atomic.Int64
+	var stop atomic.Bool
+	var signals sync.WaitGroup
+	signals.Add(1)
+	go func() {
+		defer signals.Done()
+		for !stop.Load() {
+			_ = syscall.Kill(os.Getpid(), syscall.SIGWINCH)
+		}
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var wg sync.WaitGroup
+		for i := 0; i < writers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for ws.Stats().Rotations < rotations {
+					if _, err := ws.Write([]byte("line\n")); err != nil {
+						t.Error(err)
+						return
+					}
+					written.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+	}()
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("writes deadlocked with concurrent reopens")
+	}
+	stop.Store(true)
+	signals.Wait()
+	reopentest.MustClose(t, ws)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(b, []byte("line\n")); int64(n) != written.Load() {
+		t.Errorf("%d lines in the file, want %d", n, written.Load())
+	}
+}
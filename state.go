@@ -0,0 +1,102 @@
+package reopen
+
+import (
+	"fmt"
+	"time"
+)
+
+// State is the lifecycle state of a ReopenableWriteSyncer.
+//
+//	Initializing --open--> Open --reopen--> Rotating --ok--> Open
+//	                        |                  |
+//	                        |                  +--failure--> Error --reopen ok--> Open
+//	                        |
+//	                        +--Close--> Draining --files closed--> Closed
+//	                        |
+//	                        +--no write--> Idle --write--> Open
+//
+// Error, Rotating and Idle go to Draining on Close as well.
+type State int32
+
+const (
+	// StateInitializing is the state while the writer is created.
+	StateInitializing State = iota
+	// StateOpen is the normal state, writes go to the current file.
+	StateOpen
+	// StateRotating is the state while the new file is opened.
+	StateRotating
+	// StateDraining is the state while Close waits for the in-flight writes and the background goroutines.
+	StateDraining
+	// StateClosed is the state after Close.
+	StateClosed
+	// StateError is the state after a failed reopen, writes still go to the previous file.
+	StateError
+	// StateIdle is the state after WithInactivityClose closed the file, the next write opens it again.
+	StateIdle
+
+	maxState
+)
+
+var stateNames = [maxState]string{
+	StateInitializing: "initializing",
+	StateOpen:         "open",
+	StateRotating:     "rotating",
+	StateDraining:     "draining",
+	StateClosed:       "closed",
+	StateError:        "error",
+	StateIdle:         "idle",
+}
+
+func (s State) String() string {
+	if s < 0 || s >= maxState {
+		return "unknown"
+	}
+	return stateNames[s]
+}
+
+// MarshalText implements encoding.TextMarshaler, it returns the name of s.
+func (s State) MarshalText() ([]byte, error) {
+	if s < 0 || s >= maxState {
+		return nil, fmt.Errorf("reopen: invalid state %d", int32(s))
+	}
+	return []byte(stateNames[s]), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, it parses a name returned by MarshalText.
+func (s *State) UnmarshalText(b []byte) error {
+	for i, name := range stateNames {
+		if string(b) == name {
+			*s = State(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("reopen: unknown state %q", b)
+}
+
+// StateTransition is sent to the state stream on every state change, see WithStateStream.
+type StateTransition struct {
+	From State
+	To   State
+	Time time.Time
+}
+
+// State returns the current state of the writer, StateClosed for a nil writer.
+func (ws *ReopenableWriteSyncer) State() State {
+	if ws == nil {
+		return StateClosed
+	}
+	return State(ws.state.Load())
+}
+
+// setState moves the writer to s and reports the transition to the state stream.
+func (ws *ReopenableWriteSyncer) setState(s State) {
+	from := State(ws.state.Swap(int32(s)))
+	if from == s || ws.stateStream == nil {
+		return
+	}
+	select {
+	case ws.stateStream <- StateTransition{From: from, To: s, Time: ws.clock.Now()}:
+	default:
+		ws.droppedEvents.Add(1)
+	}
+}
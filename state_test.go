@@ -0,0 +1,67 @@
+package reopen_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/owarai/reopen"
+)
+
+func TestStateText(t *testing.T) {
+	tests := []struct {
+		state reopen.State
+		text  string
+	}{
+		{reopen.StateInitializing, "initializing"},
+		{reopen.StateOpen, "open"},
+		{reopen.StateRotating, "rotating"},
+		{reopen.StateDraining, "draining"},
+		{reopen.StateClosed, "closed"},
+		{reopen.StateError, "error"},
+		{reopen.StateIdle, "idle"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if s := tt.state.String(); s != tt.text {
+				t.Errorf("String returned %q", s)
+			}
+			b, err := tt.state.MarshalText()
+			if err != nil || string(b) != tt.text {
+				t.Fatalf("MarshalText returned %q, %v", b, err)
+			}
+			var s reopen.State
+			if err := s.UnmarshalText(b); err != nil || s != tt.state {
+				t.Errorf("UnmarshalText returned %v, %v", s, err)
+			}
+			// encoding/json uses the text form for values and map keys
+			b, err = json.Marshal(map[reopen.State]reopen.State{tt.state: tt.state})
+			if want := `{"` + tt.text + `":"` + tt.text + `"}`; err != nil || string(b) != want {
+				t.Fatalf("json.Marshal returned %s, %v, want %s", b, err, want)
+			}
+			var m map[reopen.State]reopen.State
+			if err := json.Unmarshal(b, &m); err != nil || len(m) != 1 || m[tt.state] != tt.state {
+				t.Errorf("json.Unmarshal returned %v, %v", m, err)
+			}
+		})
+	}
+}
+
+func TestStateTextInvalid(t *testing.T) {
+	for _, s := range []reopen.State{-1, reopen.StateIdle + 1} {
+		if s.String() != "unknown" {
+			t.Errorf("String of %d returned %q", int32(s), s.String())
+		}
+		if b, err := s.MarshalText(); err == nil {
+			t.Errorf("MarshalText of %d returned %q", int32(s), b)
+		}
+	}
+	for _, text := range []string{"", "unknown", "Open", "open "} {
+		s := reopen.StateOpen
+		if err := s.UnmarshalText([]byte(text)); err == nil {
+			t.Errorf("UnmarshalText accepted %q", text)
+		}
+		if s != reopen.StateOpen {
+			t.Errorf("UnmarshalText of %q changed the state to %v", text, s)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package reopen
+
+import "time"
+
+// Stats is a snapshot of the counters maintained by ReopenableWriteSyncer.
+type Stats struct {
+	// Writes is the number of Write calls.
+	Writes int64
+	// Rotations is the number of successful reopens.
+	Rotations int64
+	// DroppedEvents is the number of rotation events and state transitions dropped because their stream was full.
+	DroppedEvents int64
+	// WriteErrorCount is the number of failed writes, including payloads rejected by WithJSONValidation.
+	WriteErrorCount int64
+	// LogicalBytesWritten is the number of bytes accepted by Write.
+	LogicalBytesWritten int64
+	// PhysicalBytesWritten is the number of bytes written to the log files.
+	PhysicalBytesWritten int64
+	// TruncatedBytes is the number of bytes dropped by WithMaxLineLength.
+	TruncatedBytes int64
+	// WatcherRestartCount is the number of restarts of the signal watcher, see WithMaxRestarts.
+	WatcherRestartCount int64
+	// PendingBytes is the number of bytes accepted by Write and not yet written to the file, see PendingBytes.
+	PendingBytes int64
+	// LastRotation is when the last reopen happened, it is zero before the first one.
+	LastRotation time.Time
+}
+
+// CompressionRatio returns PhysicalBytesWritten / LogicalBytesWritten, or 1.0 before anything is written.
+// It is below 1 when the write path compresses and above 1 when it adds framing or headers.
+func (s Stats) CompressionRatio() float64 {
+	if s.LogicalBytesWritten == 0 {
+		return 1.0
+	}
+	return float64(s.PhysicalBytesWritten) / float64(s.LogicalBytesWritten)
+}
+
+// Stats returns a snapshot of the writer's counters, they are all zero if the writer has no file.
+func (ws *ReopenableWriteSyncer) Stats() Stats {
+	if ws.noFile() {
+		return Stats{}
+	}
+	var lastRotation time.Time
+	if ns := ws.lastRotation.Load(); ns != 0 {
+		lastRotation = time.Unix(0, ns)
+	}
+	return Stats{
+		Writes:               ws.writes.Load(),
+		Rotations:            ws.rotations.Load(),
+		DroppedEvents:        ws.droppedEvents.Load(),
+		WriteErrorCount:      ws.writeErrors.Load(),
+		LogicalBytesWritten:  ws.logicalBytes.Load(),
+		PhysicalBytesWritten: ws.physicalBytes.Load(),
+		TruncatedBytes:       ws.truncatedBytes.Load(),
+		WatcherRestartCount:  ws.watcherRestarts.Load(),
+		PendingBytes:         ws.pendingBytes.Load(),
+		LastRotation:         lastRotation,
+	}
+}
+
+// FileAge returns the time since the current file was opened, by the creation of the writer or the last reopen.
+// A file age above the rotation period, e.g. 25 hours for a daily rotation, reveals a rotation which did not run.
+func (ws *ReopenableWriteSyncer) FileAge() time.Duration {
+	if ws.noFile() {
+		return 0
+	}
+	return ws.clock.Now().Sub(ws.getFile().opened)
+}
+
+// FileAgeSeconds returns FileAge in seconds, as expected by Prometheus.
+func (ws *ReopenableWriteSyncer) FileAgeSeconds() float64 {
+	return ws.FileAge().Seconds()
+}
+
+// CurrentFileBytesWritten returns the number of bytes written to the current file since it was opened,
+// without the stat syscall needed to get its size.
+func (ws *ReopenableWriteSyncer) CurrentFileBytesWritten() int64 {
+	if ws.noFile() {
+		return 0
+	}
+	return ws.getFile().written.Load()
+}
+
+// PendingBytes returns the approximate number of bytes waiting to be written to the file, in the WAL queue of WithWALMode,
+// the batch of WithMicroBatcher or the buffer of WithOrderedTimestamps. It is always 0 without them,
+// a growing value reveals a stalled writer.
+func (ws *ReopenableWriteSyncer) PendingBytes() int64 {
+	if ws.noFile() {
+		return 0
+	}
+	return ws.pendingBytes.Load()
+}
+
+// CurrentFileLineCount returns the number of newlines written to the current file since it was opened.
+func (ws *ReopenableWriteSyncer) CurrentFileLineCount() int64 {
+	if ws.noFile() {
+		return 0
+	}
+	return ws.getFile().lines.Load()
+}
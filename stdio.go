@@ -0,0 +1,38 @@
+package reopen
+
+import (
+	"os"
+	"syscall"
+)
+
+// NewStderrSyncer create a writeSyncer writing to the standard error, for the deployments where the orchestrator
+// handles the logs, e.g. containers. It is a full ReopenableWriteSyncer, with its stats and event streams,
+// but Reopen, Rotate and Relocate do nothing and no signal is monitored. Sync succeeds on pipes and terminals,
+// and Close leaves the standard error open.
+// NewStderrSyncer returns nil if the standard error cannot be duplicated or an option fails.
+func NewStderrSyncer(opts ...Option) *ReopenableWriteSyncer {
+	return newStdioSyncer(os.Stderr, opts)
+}
+
+// NewStdoutSyncer is NewStderrSyncer for the standard output.
+func NewStdoutSyncer(opts ...Option) *ReopenableWriteSyncer {
+	return newStdioSyncer(os.Stdout, opts)
+}
+
+func newStdioSyncer(std *os.File, opts []Option) *ReopenableWriteSyncer {
+	fd, err := syscall.Dup(int(std.Fd()))
+	if err != nil {
+		return nil
+	}
+	f := os.NewFile(uintptr(fd), std.Name())
+	opts = append(opts, func(ws *ReopenableWriteSyncer) {
+		ws.grouped = true
+		ws.stdio = true
+	})
+	ws, err := newWriteSyncer(f.Name(), f, opts)
+	if err != nil {
+		_ = f.Close()
+		return nil
+	}
+	return ws
+}
@@ -0,0 +1,17 @@
+package reopen
+
+import (
+	"io"
+	"unsafe"
+)
+
+var _ io.StringWriter = (*ReopenableWriteSyncer)(nil)
+
+// WriteString writes s like Write without copying it to a []byte, which lets fmt.Fprintf and io.WriteString
+// skip the conversion. The write path never modifies or retains the payload, so s is passed as is.
+func (ws *ReopenableWriteSyncer) WriteString(s string) (n int, err error) {
+	if len(s) == 0 {
+		return ws.Write(nil)
+	}
+	return ws.Write(unsafe.Slice(unsafe.StringData(s), len(s)))
+}
@@ -0,0 +1,45 @@
+package reopen
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// activePath returns the path the file is opened at, the file path followed by the suffix of WithActiveSuffix.
+func (ws *ReopenableWriteSyncer) activePath() string {
+	return ws.filePath + ws.activeSuffix
+}
+
+// finishActive renames f from its active path to the path without the suffix of WithActiveSuffix, and returns
+// the final path. Nothing is renamed if the active path no longer holds f, e.g. after Rotate renamed it away.
+// The rename is a link followed by an unlink so a file already at the final path is never replaced,
+// f then keeps its active path and the error is reported to the error handler. f may have been closed by goIdle.
+func (ws *ReopenableWriteSyncer) finishActive(f *logFile) string {
+	name := f.Name()
+	if ws.activeSuffix == "" || !strings.HasSuffix(name, ws.activeSuffix) {
+		return name
+	}
+	fi, err := os.Stat(name)
+	if err != nil {
+		return name
+	}
+	cur := f.idleStat
+	if cur == nil {
+		if cur, err = f.Stat(); err != nil {
+			return name
+		}
+	}
+	if !os.SameFile(fi, cur) {
+		return name
+	}
+	final := strings.TrimSuffix(name, ws.activeSuffix)
+	if err := os.Link(name, final); err != nil {
+		ws.handleError(fmt.Errorf("reopen: rename active file: %w", err))
+		return name
+	}
+	if err := os.Remove(name); err != nil {
+		ws.handleError(fmt.Errorf("reopen: rename active file: %w", err))
+	}
+	return final
+}
@@ -0,0 +1,111 @@
+package tail
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Line is a line read by a MultiTailer.
+type Line struct {
+	// Path is the path of the file the line was read from.
+	Path string
+	// Text is the line without its newline.
+	Text string
+}
+
+// MultiTailer follows several files and merges their lines into a single channel, each file is read by its own
+// Scanner and goroutine so the rotation of a file does not affect the others.
+type MultiTailer struct {
+	lines    chan Line
+	closing  chan struct{}
+	scanners []*Scanner
+	wg       sync.WaitGroup
+	once     sync.Once
+
+	mu  sync.Mutex
+	err error
+}
+
+// NewMultiTailer returns a MultiTailer following files from their current end.
+func NewMultiTailer(files []string) (*MultiTailer, error) {
+	m := &MultiTailer{lines: make(chan Line), closing: make(chan struct{})}
+	for _, path := range files {
+		s, err := NewScanner(path, false)
+		if err != nil {
+			for _, s := range m.scanners {
+				_ = s.Close()
+			}
+			return nil, err
+		}
+		m.scanners = append(m.scanners, s)
+	}
+	for i, s := range m.scanners {
+		m.wg.Add(1)
+		go m.scan(files[i], s)
+	}
+	go func() {
+		m.wg.Wait()
+		close(m.lines)
+	}()
+	return m, nil
+}
+
+func (m *MultiTailer) scan(path string, s *Scanner) {
+	defer m.wg.Done()
+	for s.Scan() {
+		select {
+		case m.lines <- Line{Path: path, Text: s.Text()}:
+		case <-m.closing:
+			return
+		}
+	}
+	if err := s.Err(); err != nil {
+		m.mu.Lock()
+		if m.err == nil {
+			m.err = fmt.Errorf("tail: %s: %w", path, err)
+		}
+		m.mu.Unlock()
+	}
+}
+
+// Lines returns the channel of the lines read from all the files, it is closed once every file stopped being read.
+func (m *MultiTailer) Lines() <-chan Line {
+	return m.lines
+}
+
+// WriteTo writes the lines to w as "<path>: <text>", until the MultiTailer is closed or a write fails.
+func (m *MultiTailer) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+	for l := range m.lines {
+		n, err := fmt.Fprintf(w, "%s: %s\n", l.Path, l.Text)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, m.Err()
+}
+
+// Err returns the first error encountered while reading a file.
+func (m *MultiTailer) Err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.err
+}
+
+// Close stops following the files, waits for the goroutines and drops the lines not yet received.
+func (m *MultiTailer) Close() error {
+	var firstErr error
+	m.once.Do(func() {
+		close(m.closing)
+		for _, s := range m.scanners {
+			if err := s.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	})
+	for range m.lines {
+	}
+	return firstErr
+}
@@ -0,0 +1,161 @@
+// Package tail follows log files across their rotations, like tail -F.
+package tail
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// PollInterval is how often a Scanner waiting at the end of a file checks for more data.
+const PollInterval = 100 * time.Millisecond
+
+// Scanner reads the lines appended to the file at a path and follows the path across rotations: a rotation is
+// detected by comparing the file being read with the file at the path, as ReopenableWriteSyncer does, and the end
+// of the old file is read before switching to the new one. A truncated file is read again from its beginning.
+type Scanner struct {
+	scanner *bufio.Scanner
+	r       *followReader
+}
+
+// NewScanner returns a Scanner over the file at path,
+// starting at the end of the file unless fromStart is true.
+func NewScanner(path string, fromStart bool) (*Scanner, error) {
+	r, err := newFollowReader(path, fromStart)
+	if err != nil {
+		return nil, err
+	}
+	return &Scanner{scanner: bufio.NewScanner(r), r: r}, nil
+}
+
+// Scan blocks until the next line is available and reports whether it got one,
+// it returns false once the scanner is closed, or on error.
+func (s *Scanner) Scan() bool {
+	return s.scanner.Scan()
+}
+
+// Text returns the line read by the last Scan.
+func (s *Scanner) Text() string {
+	return s.scanner.Text()
+}
+
+// Err returns the first error encountered by the scanner.
+func (s *Scanner) Err() error {
+	return s.scanner.Err()
+}
+
+// Close stops following the file and unblocks Scan.
+func (s *Scanner) Close() error {
+	return s.r.Close()
+}
+
+// followReader reads a file and waits for more data at its end instead of returning io.EOF,
+// it switches to the new file at the path after a rotation.
+type followReader struct {
+	path    string
+	closing chan struct{}
+
+	mu     sync.Mutex
+	f      *os.File
+	offset int64
+	last   byte // last byte read from f
+	once   sync.Once
+}
+
+func newFollowReader(path string, fromStart bool) (*followReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &followReader{path: path, f: f, closing: make(chan struct{}), last: '\n'}
+	if !fromStart {
+		if r.offset, err = f.Seek(0, io.SeekEnd); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func (r *followReader) Read(p []byte) (int, error) {
+	for {
+		r.mu.Lock()
+		if r.f == nil {
+			r.mu.Unlock()
+			return 0, io.EOF
+		}
+		n, err := r.f.Read(p)
+		if n > 0 {
+			r.offset += int64(n)
+			r.last = p[n-1]
+		}
+		if err == io.EOF && n == 0 {
+			var switched bool
+			if switched, err = r.follow(); switched && r.last != '\n' && len(p) > 0 {
+				// ends the last line of the old file.
+				p[0], n, r.last = '\n', 1, '\n'
+			}
+		}
+		r.mu.Unlock()
+		if n > 0 || (err != nil && err != io.EOF) {
+			return n, err
+		}
+		if err == nil {
+			// switched to the new file or truncated.
+			continue
+		}
+		select {
+		case <-r.closing:
+			return 0, io.EOF
+		case <-time.After(PollInterval):
+		}
+	}
+}
+
+// follow is called at the end of the file being read, it switches to the file at the path once it is another file,
+// and it starts over a truncated file. It reports whether it switched, it returns io.EOF if nothing changed.
+func (r *followReader) follow() (bool, error) {
+	cur, err := r.f.Stat()
+	if err != nil {
+		return false, err
+	}
+	fi, err := os.Stat(r.path)
+	if err != nil {
+		return false, io.EOF // the new file is not there yet.
+	}
+	if os.SameFile(fi, cur) {
+		if cur.Size() >= r.offset {
+			return false, io.EOF
+		}
+		// truncated, e.g. by logrotate copytruncate.
+		if _, err := r.f.Seek(0, io.SeekStart); err != nil {
+			return false, err
+		}
+		r.offset = 0
+		return false, nil
+	}
+	if cur.Size() > r.offset {
+		return false, nil // written since the last read, read it first.
+	}
+	f, err := os.Open(r.path)
+	if err != nil {
+		return false, io.EOF
+	}
+	_ = r.f.Close()
+	r.f, r.offset = f, 0
+	return true, nil
+}
+
+func (r *followReader) Close() error {
+	var err error
+	r.once.Do(func() {
+		close(r.closing)
+		r.mu.Lock()
+		err = r.f.Close()
+		r.f = nil
+		r.mu.Unlock()
+	})
+	return err
+}
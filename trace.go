@@ -0,0 +1,161 @@
+package reopen
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"io"
+	"os"
+)
+
+// TraceContext identifies the trace and span a log write belongs to.
+type TraceContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+}
+
+// traceHeaderMarker starts the binary trace header written by WriteCtx, it is the ASCII record separator.
+const traceHeaderMarker = 0x1e
+
+// traceHeaderLen is the size of the binary trace header: marker, trace id and span id.
+const traceHeaderLen = 1 + 16 + 8
+
+type traceContextKey struct{}
+
+// ContextWithTrace returns a copy of ctx carrying tc, which is what the default trace extractor looks for.
+func ContextWithTrace(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, tc)
+}
+
+func traceFromContext(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(traceContextKey{}).(TraceContext)
+	return tc, ok
+}
+
+// WriteSyncer is the method set of zapcore.WriteSyncer.
+type WriteSyncer interface {
+	io.Writer
+	Sync() error
+}
+
+// CtxWriteSyncer is a WriteSyncer which can also write on behalf of a context.
+type CtxWriteSyncer interface {
+	WriteSyncer
+	WriteCtx(ctx context.Context, p []byte) (int, error)
+	// Bind returns a WriteSyncer whose Write calls WriteCtx with ctx, it can be given to zapcore.NewCore.
+	Bind(ctx context.Context) WriteSyncer
+}
+
+// NewCtxWriteSyncer bridges ws to code which needs a zapcore.WriteSyncer bound to a context.
+func NewCtxWriteSyncer(ws *ReopenableWriteSyncer) CtxWriteSyncer {
+	return ctxWriteSyncer{ws}
+}
+
+type ctxWriteSyncer struct {
+	*ReopenableWriteSyncer
+}
+
+func (c ctxWriteSyncer) Bind(ctx context.Context) WriteSyncer {
+	return boundWriteSyncer{ws: c.ReopenableWriteSyncer, ctx: ctx}
+}
+
+type boundWriteSyncer struct {
+	ws  *ReopenableWriteSyncer
+	ctx context.Context
+}
+
+func (b boundWriteSyncer) Write(p []byte) (int, error) {
+	return b.ws.WriteCtx(b.ctx, p)
+}
+
+func (b boundWriteSyncer) Sync() error {
+	return b.ws.Sync()
+}
+
+// WriteCtx writes p tagged with the trace and span found in ctx by the trace extractor, see WithTraceExtractor.
+// The ids are prepended as a binary header: 0x1e, 16 bytes trace id and 8 bytes span id.
+// With WithJSONInjection they are added as "trace_id" and "span_id" fields of the JSON object in p instead.
+// p is written unchanged if ctx carries no trace.
+//
+// If ctx can be cancelled, the write runs in a goroutine on a copy of p and WriteCtx returns ctx.Err() as soon as
+// ctx is done, e.g. while a write to a NFS file stalls. A syscall can not be cancelled: the goroutine keeps going
+// until the write completes, so every cancelled stalled write leaks a goroutine until the file answers,
+// and the payload may still reach the file after WriteCtx returned.
+func (ws *ReopenableWriteSyncer) WriteCtx(ctx context.Context, p []byte) (int, error) {
+	if ws.noFile() {
+		return 0, os.ErrClosed
+	}
+	if ctx.Done() == nil {
+		return ws.writeTraced(ctx, p)
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	type result struct {
+		n   int
+		err error
+	}
+	done := make(chan result, 1)
+	p = append([]byte(nil), p...)
+	go func() {
+		n, err := ws.writeTraced(ctx, p)
+		done <- result{n, err}
+	}()
+	select {
+	case r := <-done:
+		return r.n, r.err
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// writeTraced writes p tagged with the trace found in ctx, see WriteCtx.
+func (ws *ReopenableWriteSyncer) writeTraced(ctx context.Context, p []byte) (int, error) {
+	tc, ok := ws.traceExtractor(ctx)
+	if !ok {
+		return ws.Write(p)
+	}
+	var buf []byte
+	if ws.jsonInjection {
+		buf, ok = injectTrace(p, tc)
+		if !ok {
+			return ws.Write(p)
+		}
+	} else {
+		buf = make([]byte, 0, traceHeaderLen+len(p))
+		buf = append(buf, traceHeaderMarker)
+		buf = append(buf, tc.TraceID[:]...)
+		buf = append(buf, tc.SpanID[:]...)
+		buf = append(buf, p...)
+	}
+	n, err := ws.Write(buf)
+	if err != nil {
+		// report the bytes of p which made it to the file.
+		n -= len(buf) - len(p)
+		if n < 0 {
+			n = 0
+		}
+		return n, err
+	}
+	return len(p), nil
+}
+
+// injectTrace adds the trace fields at the start of the JSON object in p, it reports false if p is not an object.
+func injectTrace(p []byte, tc TraceContext) ([]byte, bool) {
+	trimmed := bytes.TrimLeft(p, " \t\r\n")
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return nil, false
+	}
+	rest := trimmed[1:]
+	buf := make([]byte, 0, len(p)+64)
+	buf = append(buf, `{"trace_id":"`...)
+	buf = append(buf, hex.EncodeToString(tc.TraceID[:])...)
+	buf = append(buf, `","span_id":"`...)
+	buf = append(buf, hex.EncodeToString(tc.SpanID[:])...)
+	buf = append(buf, '"')
+	if r := bytes.TrimLeft(rest, " \t\r\n"); len(r) > 0 && r[0] != '}' {
+		buf = append(buf, ',')
+	}
+	buf = append(buf, rest...)
+	return buf, true
+}
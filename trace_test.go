@@ -0,0 +1,81 @@
+package reopen_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/owarai/reopen"
+)
+
+type tracedKey struct{}
+
+func TestWriteCtx(t *testing.T) {
+	tc := reopen.TraceContext{
+		TraceID: [16]byte{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:  [8]byte{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+	}
+	const ids = `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7"`
+	header := append([]byte{0x1e}, append(tc.TraceID[:], tc.SpanID[:]...)...)
+	traced := reopen.ContextWithTrace(context.Background(), tc)
+	cancellable, cancel := context.WithCancel(traced)
+	defer cancel()
+	tests := []struct {
+		name    string
+		opts    []reopen.Option
+		ctx     context.Context
+		payload string
+		want    string
+	}{
+		{"binary header", nil, traced, "msg\n", string(header) + "msg\n"},
+		{"cancellable context", nil, cancellable, "msg\n", string(header) + "msg\n"},
+		{"no trace", nil, context.Background(), "msg\n", "msg\n"},
+		{"JSON object", []reopen.Option{reopen.WithJSONInjection()}, traced,
+			`{"msg":"a"}` + "\n", `{` + ids + `,"msg":"a"}` + "\n"},
+		{"empty JSON object", []reopen.Option{reopen.WithJSONInjection()}, traced,
+			" {}\n", `{` + ids + "}\n"},
+		{"not a JSON object", []reopen.Option{reopen.WithJSONInjection()}, traced, "[1]\n", "[1]\n"},
+		{"JSON without trace", []reopen.Option{reopen.WithJSONInjection()}, context.Background(),
+			`{"msg":"a"}` + "\n", `{"msg":"a"}` + "\n"},
+		{"custom extractor", []reopen.Option{reopen.WithTraceExtractor(func(ctx context.Context) (reopen.TraceContext, bool) {
+			return tc, ctx.Value(tracedKey{}) != nil
+		})}, context.WithValue(context.Background(), tracedKey{}, true), "msg\n", string(header) + "msg\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log")
+			ws, err := reopen.NewWithOptions(path, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ws.Close()
+			// the bound writer is what zapcore.NewCore is given
+			n, err := reopen.NewCtxWriteSyncer(ws).Bind(tt.ctx).Write([]byte(tt.payload))
+			if err != nil || n != len(tt.payload) {
+				t.Fatalf("Write returned %d, %v, want %d", n, err, len(tt.payload))
+			}
+			if b, err := os.ReadFile(path); err != nil || !bytes.Equal(b, []byte(tt.want)) {
+				t.Errorf("file holds %q, %v, want %q", b, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteCtxCancelled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	ws, err := reopen.NewWithOptions(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ws.WriteCtx(ctx, []byte("msg\n")); err != context.Canceled {
+		t.Errorf("WriteCtx returned %v, want %v", err, context.Canceled)
+	}
+	if b, err := os.ReadFile(path); err != nil || len(b) != 0 {
+		t.Errorf("file holds %q, %v", b, err)
+	}
+}
@@ -0,0 +1,118 @@
+package reopen
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+	"unicode/utf8"
+)
+
+const defaultTruncationMarker = "[TRUNCATED]"
+
+// transform applies the payload transformations enabled by the options to p, in a fixed order.
+// It returns p itself when nothing changes, and the buffer of WithBufferPool holding the result if any,
+// which goes back to the pool with putBuffer once the result is written.
+func (ws *ReopenableWriteSyncer) transform(p []byte) ([]byte, *[]byte) {
+	if len(ws.filteredFields) > 0 {
+		p = ws.filterFields(p)
+	}
+	if len(ws.newlineFrom) > 0 && bytes.Contains(p, ws.newlineFrom) {
+		p = bytes.ReplaceAll(p, ws.newlineFrom, ws.newlineTo)
+	}
+	if ws.maxLineLength > 0 && len(p) > ws.maxLineLength {
+		p = ws.truncateLine(p)
+	}
+	if ws.lokiLabels != nil {
+		p = ws.wrapLoki(p)
+	}
+	if len(ws.staticPrefix) == 0 && ws.framer == nil {
+		return p, nil
+	}
+	return ws.assemble(p)
+}
+
+// assemble returns p with the static prefix and the framing in a single buffer,
+// taken from the pool of WithBufferPool if any.
+func (ws *ReopenableWriteSyncer) assemble(p []byte) ([]byte, *[]byte) {
+	var header, trailer []byte
+	if ws.framer != nil {
+		header, trailer = ws.framer.Header(len(ws.staticPrefix)+len(p)), ws.framer.Trailer
+	}
+	size := len(header) + len(ws.staticPrefix) + len(p) + len(trailer)
+	var buf *[]byte
+	var out []byte
+	if ws.bufferPool != nil {
+		buf = ws.bufferPool.Get().(*[]byte)
+		out = (*buf)[:0]
+	} else {
+		out = make([]byte, 0, size)
+	}
+	out = append(out, header...)
+	out = append(out, ws.staticPrefix...)
+	out = append(out, p...)
+	out = append(out, trailer...)
+	if buf != nil {
+		*buf = out
+	}
+	return out, buf
+}
+
+// putBuffer gives buf, returned by transform, back to the pool of WithBufferPool.
+func (ws *ReopenableWriteSyncer) putBuffer(buf *[]byte) {
+	if buf == nil {
+		return
+	}
+	*buf = (*buf)[:0]
+	ws.bufferPool.Put(buf)
+}
+
+// defaultBufferPool is the pool of WithBufferPool(nil).
+var defaultBufferPool = NewBufferPool(defaultBufferCapacity)
+
+const defaultBufferCapacity = 4096
+
+// NewBufferPool returns a pool of *[]byte buffers of capacity bytes, to be given to WithBufferPool.
+func NewBufferPool(capacity int) *sync.Pool {
+	return &sync.Pool{New: func() any {
+		b := make([]byte, 0, capacity)
+		return &b
+	}}
+}
+
+// truncateLine keeps the first maxLineLength bytes of p followed by the truncation marker,
+// and the trailing newline of p if any. The cut never splits a UTF-8 sequence of a valid UTF-8 payload.
+func (ws *ReopenableWriteSyncer) truncateLine(p []byte) []byte {
+	cut := ws.maxLineLength
+	if utf8.Valid(p) {
+		for cut > 0 && !utf8.RuneStart(p[cut]) {
+			cut--
+		}
+	}
+	newline := p[len(p)-1] == '\n'
+	ws.truncatedBytes.Add(int64(len(p) - cut))
+
+	out := make([]byte, 0, cut+len(ws.truncationMarker)+1)
+	out = append(out, p[:cut]...)
+	out = append(out, ws.truncationMarker...)
+	if newline {
+		out = append(out, '\n')
+	}
+	return out
+}
+
+// wrapLoki returns p as the "log" of a JSON object holding the labels of WithLokiLabels,
+// p is embedded as is if it is JSON and as a string otherwise.
+func (ws *ReopenableWriteSyncer) wrapLoki(p []byte) []byte {
+	line := bytes.TrimRight(p, "\n")
+	out := make([]byte, 0, len(`{"labels":,"log":}`)+len(ws.lokiLabels)+len(line)+3)
+	out = append(out, `{"labels":`...)
+	out = append(out, ws.lokiLabels...)
+	out = append(out, `,"log":`...)
+	if json.Valid(line) {
+		out = append(out, line...)
+	} else {
+		s, _ := json.Marshal(string(line))
+		out = append(out, s...)
+	}
+	return append(out, "}\n"...)
+}
@@ -0,0 +1,101 @@
+package reopen_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/owarai/reopen"
+	"github.com/owarai/reopen/reopentest"
+)
+
+func TestLokiLabels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	labels := map[string]string{"app": "api", "env": "prod"}
+	ws, err := reopen.NewWithOptions(path, reopen.WithLokiLabels(labels), reopen.WithFileHeader([]byte("# api logs\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	payloads := []string{
+		`{"level":"info","msg":"zap line"}` + "\n", // zap ends every entry with a newline
+		`{"level":"warn","msg":"no newline"}`,
+		"plain \"text\"\n",
+	}
+	for _, p := range payloads {
+		if _, err := ws.Write([]byte(p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reopentest.MustClose(t, ws)
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	if !s.Scan() || s.Text() != "# api logs" {
+		t.Fatalf("file header %q, want %q", s.Text(), "# api logs")
+	}
+	if !s.Scan() {
+		t.Fatal("no labels header")
+	}
+	var header map[string]map[string]string
+	if err := json.Unmarshal(s.Bytes(), &header); err != nil || !reflect.DeepEqual(header, map[string]map[string]string{"labels": labels}) {
+		t.Fatalf("labels header %q: %v", s.Text(), err)
+	}
+	want := []any{
+		map[string]any{"level": "info", "msg": "zap line"},
+		map[string]any{"level": "warn", "msg": "no newline"},
+		`plain "text"`,
+	}
+	for _, w := range want {
+		if !s.Scan() {
+			t.Fatalf("missing line for %v", w)
+		}
+		var line struct {
+			Labels map[string]string `json:"labels"`
+			Log    any               `json:"log"`
+		}
+		if err := json.Unmarshal(s.Bytes(), &line); err != nil {
+			t.Fatalf("line %q: %v", s.Text(), err)
+		}
+		if !reflect.DeepEqual(line.Labels, labels) || !reflect.DeepEqual(line.Log, w) {
+			t.Errorf("line %q, want labels %v and log %v", s.Text(), labels, w)
+		}
+	}
+	if s.Scan() {
+		t.Errorf("unexpected line %q", s.Text())
+	}
+}
+
+// BenchmarkTransformPath measures the allocations of a write through WithStaticPrefix, with and without
+// WithBufferPool.
+func BenchmarkTransformPath(b *testing.B) {
+	for _, bc := range []struct {
+		name string
+		opts []reopen.Option
+	}{
+		{"no-pool", nil},
+		{"pool", []reopen.Option{reopen.WithBufferPool(nil)}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			opts := append([]reopen.Option{reopen.WithStaticPrefix([]byte("host=web-1 "))}, bc.opts...)
+			ws, err := reopen.NewWithOptions(filepath.Join(b.TempDir(), "app.log"), opts...)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer reopentest.MustClose(b, ws)
+			b.SetBytes(int64(len(benchLine)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := ws.Write(benchLine); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package reopen
+
+import (
+	"os"
+	"time"
+)
+
+// validate reopens the file with TriggerValidation every validationInterval
+// if the current file is no longer the one at the file path.
+func (ws *ReopenableWriteSyncer) validate() {
+	ticker := time.NewTicker(ws.validationInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ws.closing:
+			return
+		case <-ticker.C:
+			f := ws.acquireIfOpen()
+			if f == nil {
+				continue // idle, the next write opens the file at the path anyway
+			}
+			stale := ws.stale(f)
+			f.release()
+			if stale {
+				if err := ws.rotate(TriggerValidation, nil); err != nil && err != os.ErrClosed {
+					ws.handleError(err)
+				}
+			}
+		}
+	}
+}
+
+// stale reports whether f is no longer the file at its path, e.g. because the path has been removed or replaced.
+func (ws *ReopenableWriteSyncer) stale(f *logFile) bool {
+	cur, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	fi, err := os.Stat(f.Name())
+	if err != nil {
+		return os.IsNotExist(err)
+	}
+	return !os.SameFile(cur, fi)
+}
@@ -0,0 +1,172 @@
+package reopen
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+// walFrameHeaderLen is the size of the big-endian payload length prefixing every WAL entry.
+const walFrameHeaderLen = 4
+
+// ReplayWAL appends the entries of the WAL at walPath to mainPath, fsyncs mainPath and empties the WAL.
+// A truncated last entry, left by a crash in the middle of a write, is dropped.
+// A missing WAL is not an error.
+func ReplayWAL(walPath, mainPath string) error {
+	wal, err := os.OpenFile(walPath, os.O_RDWR, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer wal.Close()
+
+	main, err := os.OpenFile(mainPath, openFlag, defaultFileMode)
+	if err != nil {
+		return err
+	}
+	r := bufio.NewReader(wal)
+	var header [walFrameHeaderLen]byte
+	var payload []byte
+	for {
+		if _, err = io.ReadFull(r, header[:]); err != nil {
+			break
+		}
+		n := binary.BigEndian.Uint32(header[:])
+		if cap(payload) < int(n) {
+			payload = make([]byte, n)
+		}
+		payload = payload[:n]
+		if _, err = io.ReadFull(r, payload); err != nil {
+			break
+		}
+		if _, err = main.Write(payload); err != nil {
+			_ = main.Close()
+			return err
+		}
+	}
+	if err != io.EOF && err != io.ErrUnexpectedEOF {
+		_ = main.Close()
+		return err
+	}
+	if err := main.Sync(); err != nil {
+		_ = main.Close()
+		return err
+	}
+	if err := main.Close(); err != nil {
+		return err
+	}
+	return wal.Truncate(0)
+}
+
+// openWAL replays what a previous run left in the WAL and opens it for the entries of this run.
+func (ws *ReopenableWriteSyncer) openWAL() error {
+	if err := ReplayWAL(ws.walPath, ws.activePath()); err != nil {
+		return err
+	}
+	wal, err := os.OpenFile(ws.walPath, openFlag, ws.fileMode)
+	if err != nil {
+		return err
+	}
+	ws.wal = wal
+	ws.walCond = sync.NewCond(&ws.walMu)
+	ws.walDone = make(chan struct{})
+	return nil
+}
+
+// writeWAL makes p durable in the WAL and queues it for the main file. Both happen under walMu, so the entries
+// reach the main file in the order of the WAL. It waits while walQueueSize entries are queued.
+func (ws *ReopenableWriteSyncer) writeWAL(p []byte) (int, error) {
+	frame := make([]byte, walFrameHeaderLen+len(p))
+	binary.BigEndian.PutUint32(frame, uint32(len(p)))
+	copy(frame[walFrameHeaderLen:], p)
+
+	ws.walMu.Lock()
+	defer ws.walMu.Unlock()
+	for len(ws.walQueue) >= walQueueSize && !ws.closed() {
+		ws.walCond.Wait()
+	}
+	if ws.closed() {
+		return 0, os.ErrClosed
+	}
+	_, err := ws.wal.Write(frame)
+	if err == nil {
+		err = ws.wal.Sync()
+	}
+	if err != nil {
+		ws.writeErrors.Add(1)
+		return 0, err
+	}
+	ws.walSize += int64(len(frame))
+	ws.walUnflushed.Add(1)
+	ws.pendingBytes.Add(int64(len(p)))
+	ws.walQueue = append(ws.walQueue, frame[walFrameHeaderLen:])
+	ws.walCond.Broadcast()
+	return len(p), nil
+}
+
+// flushWAL copies the queued WAL entries to the main file until the writer is closed and the queue drained.
+func (ws *ReopenableWriteSyncer) flushWAL() {
+	defer close(ws.walDone)
+	for {
+		ws.walMu.Lock()
+		for len(ws.walQueue) == 0 && !ws.closed() {
+			ws.walCond.Wait()
+		}
+		if len(ws.walQueue) == 0 {
+			ws.walMu.Unlock()
+			return
+		}
+		p := ws.walQueue[0]
+		ws.walQueue[0] = nil
+		ws.walQueue = ws.walQueue[1:]
+		ws.walCond.Broadcast()
+		ws.walMu.Unlock()
+		ws.flushWALEntry(p)
+	}
+}
+
+func (ws *ReopenableWriteSyncer) flushWALEntry(p []byte) {
+	if _, err := ws.writeFile(p); err != nil {
+		ws.handleError(err)
+	}
+	ws.pendingBytes.Add(-int64(len(p)))
+	ws.walUnflushed.Add(-1)
+	ws.walMu.Lock()
+	full := ws.walSize >= ws.walMaxSize
+	ws.walMu.Unlock()
+	if full {
+		ws.checkpointWAL()
+	}
+}
+
+// checkpointWAL fsyncs the main file and empties the WAL if every entry in it reached the main file.
+func (ws *ReopenableWriteSyncer) checkpointWAL() {
+	if err := ws.syncFile(); err != nil {
+		ws.handleError(err)
+		return
+	}
+	ws.walMu.Lock()
+	defer ws.walMu.Unlock()
+	if ws.walUnflushed.Load() != 0 {
+		return
+	}
+	if err := ws.wal.Truncate(0); err != nil {
+		ws.handleError(err)
+		return
+	}
+	ws.walSize = 0
+}
+
+// closeWAL waits for the queued entries to reach the main file, then empties and closes the WAL.
+func (ws *ReopenableWriteSyncer) closeWAL() {
+	<-ws.walDone
+	ws.checkpointWAL()
+	if err := ws.wal.Close(); err != nil {
+		ws.handleError(err)
+	}
+}
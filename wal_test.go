@@ -0,0 +1,134 @@
+package reopen_test
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/owarai/reopen"
+)
+
+// walFrame returns the WAL entry of payload.
+func walFrame(payload string) []byte {
+	frame := make([]byte, 4, 4+len(payload))
+	binary.BigEndian.PutUint32(frame, uint32(len(payload)))
+	return append(frame, payload...)
+}
+
+func TestReplayWAL(t *testing.T) {
+	full := string(walFrame("one\n")) + string(walFrame("two\n"))
+	tests := []struct {
+		name string
+		wal  *string // nil for no WAL
+		want string
+	}{
+		{"no WAL", nil, "existing\n"},
+		{"empty WAL", ptr(""), "existing\n"},
+		{"complete entries", ptr(full), "existing\none\ntwo\n"},
+		{"truncated header", ptr(full + "\x00\x00"), "existing\none\ntwo\n"},
+		{"truncated payload", ptr(full + string(walFrame("three\n")[:7])), "existing\none\ntwo\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			walPath, mainPath := filepath.Join(dir, "app.wal"), filepath.Join(dir, "app.log")
+			if err := os.WriteFile(mainPath, []byte("existing\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if tt.wal != nil {
+				if err := os.WriteFile(walPath, []byte(*tt.wal), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := reopen.ReplayWAL(walPath, mainPath); err != nil {
+				t.Fatal(err)
+			}
+			if b, err := os.ReadFile(mainPath); err != nil || string(b) != tt.want {
+				t.Errorf("main file holds %q, %v, want %q", b, err, tt.want)
+			}
+			if fi, err := os.Stat(walPath); tt.wal != nil && (err != nil || fi.Size() != 0) {
+				t.Errorf("WAL not emptied: %v, %v", fi, err)
+			}
+		})
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
+
+func TestWALReplayedAfterCrash(t *testing.T) {
+	dir := t.TempDir()
+	walPath, mainPath := filepath.Join(dir, "app.wal"), filepath.Join(dir, "app.log")
+	// a crash after the entries reached the WAL but only the first one the file, in the middle of a third entry
+	if err := os.WriteFile(mainPath, []byte("one\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	crashed := string(walFrame("one\n")) + string(walFrame("two\n")) + string(walFrame("three\n")[:6])
+	if err := os.WriteFile(walPath, []byte(crashed), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ws, err := reopen.NewWithOptions(mainPath, reopen.WithWALMode(walPath, 1<<20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeLines(t, ws, "after restart\n")
+	if err := ws.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// the entries already in the file are duplicated, never lost
+	if b, err := os.ReadFile(mainPath); err != nil || string(b) != "one\none\ntwo\nafter restart\n" {
+		t.Errorf("main file holds %q, %v", b, err)
+	}
+	if fi, err := os.Stat(walPath); err != nil || fi.Size() != 0 {
+		t.Errorf("WAL not emptied on Close: %v, %v", fi, err)
+	}
+}
+
+func TestWALOrderMatchesFile(t *testing.T) {
+	dir := t.TempDir()
+	walPath, mainPath := filepath.Join(dir, "app.wal"), filepath.Join(dir, "app.log")
+	ws, err := reopen.NewWithOptions(mainPath, reopen.WithWALMode(walPath, 1<<30))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				if _, err := ws.Write([]byte(fmt.Sprintf("%d-%d\n", g, i))); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	for deadline := time.Now().Add(5 * time.Second); ws.PendingBytes() != 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("the WAL entries did not reach the file")
+		}
+	}
+
+	wal, err := os.ReadFile(walPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries strings.Builder
+	for len(wal) >= 4 {
+		n := binary.BigEndian.Uint32(wal)
+		entries.Write(wal[4 : 4+n])
+		wal = wal[4+n:]
+	}
+	if b, err := os.ReadFile(mainPath); err != nil || string(b) != entries.String() {
+		t.Errorf("the file order differs from the WAL order: %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package reopen
+
+import (
+	"os"
+	"time"
+)
+
+// watcherCoalesceWindow is how long the file watcher waits after an event for the events which follow it,
+// e.g. a rename followed by a create, before reopening once.
+const watcherCoalesceWindow = 100 * time.Millisecond
+
+// fileWatcher reports the changes of the file path, see WithFileWatcher.
+type fileWatcher interface {
+	// run delivers the events until close is called.
+	run()
+	// watch watches path instead of the previous path, e.g. after Relocate.
+	watch(path string) error
+	events() <-chan struct{}
+	close() error
+}
+
+// watchFile reopens the file with TriggerWatcher when w reports that the file path no longer holds the current file.
+func (ws *ReopenableWriteSyncer) watchFile(w fileWatcher) {
+	defer w.close()
+	var fire <-chan time.Time
+	for {
+		select {
+		case <-ws.closing:
+			return
+		case <-w.events():
+			if fire == nil {
+				fire = time.After(watcherCoalesceWindow)
+			}
+		case <-fire:
+			fire = nil
+			f := ws.acquireIfOpen()
+			if f == nil {
+				continue // idle, the next write opens the file at the path anyway
+			}
+			stale := ws.stale(f)
+			f.release()
+			if stale {
+				if err := ws.rotate(TriggerWatcher, nil); err != nil && err != os.ErrClosed {
+					ws.handleError(err)
+				}
+			}
+		}
+	}
+}
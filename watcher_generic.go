@@ -0,0 +1,46 @@
+//go:build !linux
+
+package reopen
+
+import "time"
+
+const watcherPollInterval = time.Second
+
+// pollingWatcher reports an event every poll interval, watchFile then compares the file path with the current file.
+type pollingWatcher struct {
+	ch   chan struct{}
+	done chan struct{}
+}
+
+func newFileWatcher(string) (fileWatcher, error) {
+	return &pollingWatcher{ch: make(chan struct{}, 1), done: make(chan struct{})}, nil
+}
+
+func (w *pollingWatcher) run() {
+	ticker := time.NewTicker(watcherPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			select {
+			case w.ch <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+func (w *pollingWatcher) watch(string) error {
+	return nil // watchFile compares the current path on every poll
+}
+
+func (w *pollingWatcher) events() <-chan struct{} {
+	return w.ch
+}
+
+func (w *pollingWatcher) close() error {
+	close(w.done)
+	return nil
+}
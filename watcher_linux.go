@@ -0,0 +1,88 @@
+package reopen
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// inotifyWatcher watches the directory of the file for the files created or moved to the file path.
+type inotifyWatcher struct {
+	f  *os.File
+	fd int
+	ch chan struct{}
+
+	mu   sync.Mutex
+	wd   int // watch descriptor of the directory of the file
+	name string
+}
+
+func newFileWatcher(path string) (fileWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	w := &inotifyWatcher{fd: fd, wd: -1, ch: make(chan struct{}, 1)}
+	if err := w.watch(path); err != nil {
+		_ = syscall.Close(fd)
+		return nil, err
+	}
+	// the descriptor is non-blocking, so reads go through the runtime poller and Close interrupts them.
+	w.f = os.NewFile(uintptr(fd), "inotify")
+	return w, nil
+}
+
+func (w *inotifyWatcher) watch(path string) error {
+	wd, err := syscall.InotifyAddWatch(w.fd, filepath.Dir(path), syscall.IN_CREATE|syscall.IN_MOVED_TO)
+	if err != nil {
+		return os.NewSyscallError("inotify_add_watch", err)
+	}
+	w.mu.Lock()
+	old := w.wd
+	w.wd, w.name = wd, filepath.Base(path)
+	w.mu.Unlock()
+	if old >= 0 && old != wd { // the same directory keeps its watch descriptor
+		_, _ = syscall.InotifyRmWatch(w.fd, uint32(old))
+	}
+	return nil
+}
+
+func (w *inotifyWatcher) run() {
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := w.f.Read(buf)
+		if err != nil {
+			return
+		}
+		for off := 0; off+syscall.SizeofInotifyEvent <= n; {
+			ev := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			nameStart := off + syscall.SizeofInotifyEvent
+			name := bytes.TrimRight(buf[nameStart:nameStart+int(ev.Len)], "\x00")
+			if w.matches(int(ev.Wd), name) {
+				select {
+				case w.ch <- struct{}{}:
+				default:
+				}
+			}
+			off = nameStart + int(ev.Len)
+		}
+	}
+}
+
+// matches reports whether the event of the watch descriptor wd on name is about the file path.
+func (w *inotifyWatcher) matches(wd int, name []byte) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return wd == w.wd && string(name) == w.name
+}
+
+func (w *inotifyWatcher) events() <-chan struct{} {
+	return w.ch
+}
+
+func (w *inotifyWatcher) close() error {
+	return w.f.Close()
+}
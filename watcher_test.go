@@ -0,0 +1,40 @@
+package reopen_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/owarai/reopen"
+)
+
+func TestFileWatcherFollowsRelocate(t *testing.T) {
+	ws, err := reopen.NewWithOptions(filepath.Join(t.TempDir(), "app.log"), reopen.WithFileWatcher())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	newPath := filepath.Join(t.TempDir(), "app.log")
+	if err := ws.Relocate(newPath); err != nil {
+		t.Fatal(err)
+	}
+	before := ws.Stats().Rotations
+
+	// logrotate without postrotate script: the file is moved away and a new one created at the path
+	if err := os.Rename(newPath, newPath+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(newPath, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); ws.Stats().Rotations == before; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("the rotation of the relocated file was not detected")
+		}
+	}
+	writeLines(t, ws, "line\n")
+	if b, err := os.ReadFile(newPath); err != nil || string(b) != "line\n" {
+		t.Errorf("new file holds %q, %v", b, err)
+	}
+}
@@ -5,22 +5,175 @@
 // This zapcore.WriteSyncer implementation continues to write log to dest file until the target file is rotated by logrotate,
 // then it receives the syscall triggered by the postrotate configured in logrotate, opens a new file and continues to write.
 //
+// Signals are delivered by os/signal to a channel read by a regular goroutine, so no code of this package runs
+// in a signal handler. Reopening never blocks Write: the new file is opened and published with an atomic store
+// while writes continue on the old file, writes only take a read lock of the file they write to,
+// which is write locked for an instant when that file is retired.
+//
+// The caller recorded in zap entries is resolved by zap.Logger before the entry is encoded,
+// a WriteSyncer only receives the encoded bytes and can not change it.
+// Wrapping this WriteSyncer with other adapters never shifts the caller, use zap.AddCallerSkip
+// on the logger when the logging call itself is wrapped.
+//
+// ReopenableWriteSyncer is a plain io.Writer as well, so it can be used directly with log.SetOutput or fmt.Fprintln.
+//
 // See github.com/owarai/reopen/example module for usage examples.
 package reopen
 
 import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
 	"os"
 	"os/signal"
+	"runtime/pprof"
+	"sync"
 	"sync/atomic"
 	"syscall"
 	"time"
 )
 
 type ReopenableWriteSyncer struct {
-	filePath  string
-	fileMode  os.FileMode
-	reopenSig chan os.Signal
-	cur       atomic.Value // *os.File
+	filePath        string
+	absolutePath    bool
+	resolveSymlinks bool
+	fileMode        os.FileMode
+	openFlag        int
+	cloexec         bool
+	odirect         bool
+	directAlignment int
+	alignMu         sync.Mutex
+	alignBuf        []byte
+	signals         []os.Signal
+	grouped         bool // signals are handled by a RotationGroup
+	stdio           bool // writes to a standard stream, never reopened
+	reopenSig       chan os.Signal
+	sigBufferSize   int
+	reloadMu        sync.Mutex
+	cur             atomic.Value // *logFile
+
+	drainTimeout   time.Duration
+	closeStrategy  CloseStrategy
+	truncateOnOpen bool
+	openRetry      *constantRetryPolicy // initial open, see WithOpenRetry
+	retryPolicy    RetryPolicy          // initial open unless openRetry is set, and reopens
+	chmod          *os.FileMode
+	guardInterval  time.Duration
+
+	validationInterval    time.Duration
+	fileWatcher           bool
+	watcher               fileWatcher // set by WithFileWatcher, rewatched when the file path changes
+	truncationInterval    time.Duration
+	copyTruncateDetection bool
+	lastRotation          atomic.Int64 // unix nano
+
+	asyncSync     bool
+	syncInterval  time.Duration
+	syncMinBytes  int64
+	pendingSync   atomic.Bool
+	unsyncedBytes atomic.Int64
+	syncKick      chan struct{}
+
+	errorHandler func(error)
+	closeErrMu   sync.Mutex
+	closeErr     error
+	nfsAware     bool
+	networkFS    bool
+
+	filteredFields   map[string]bool
+	fastFieldFilter  bool
+	newlineFrom      []byte
+	newlineTo        []byte
+	maxLineLength    int
+	atomicWriteSize  int
+	chunkMu          sync.Mutex
+	framer           *Framer
+	truncationMarker string
+
+	jsonValidation  bool
+	jsonlValidation bool
+	quarantine      io.Writer
+
+	traceExtractor func(ctx context.Context) (TraceContext, bool)
+	jsonInjection  bool
+
+	events          chan<- RotationEvent
+	state           atomic.Int32
+	stateStream     chan<- StateTransition
+	rotations       atomic.Int64
+	watcherRestarts atomic.Int64
+	watcherRunning  atomic.Bool
+	syncs           atomic.Int64
+	droppedEvents   atomic.Int64
+	writes          atomic.Int64
+	writeErrors     atomic.Int64
+	logicalBytes    atomic.Int64
+	physicalBytes   atomic.Int64
+	truncatedBytes  atomic.Int64
+	pendingBytes    atomic.Int64
+
+	walPath      string
+	walMaxSize   int64
+	wal          *os.File
+	walMu        sync.Mutex
+	walSize      int64
+	walUnflushed atomic.Int64
+	walQueue     [][]byte   // entries in the WAL not yet copied to the file, guarded by walMu
+	walCond      *sync.Cond // signals the changes of walQueue and the close, on walMu
+	walDone      chan struct{}
+
+	rotating      atomic.Bool
+	namer         RotationNamer
+	maxBackups    int
+	maxTotalSize  int64
+	postRotate    []func(ev RotationEvent)
+	postRotateCmd *postRotateCommand
+	notifyAddr    string
+	notifyConn    net.Conn
+	dedup         *deduplicator
+	activeSuffix  string
+	rotateAlone   bool
+	maxRestarts   int
+	maxFailures   int
+	batcher       *microBatcher
+	orderer       *timestampOrderer
+	staticPrefix  []byte
+	bufferPool    *sync.Pool
+	encryptionKey []byte
+	aead          cipher.AEAD
+	httpTrigger   *httpTrigger
+	archiver      *Archiver
+	shards        int
+	maxJitter     time.Duration
+	idleTimeout   time.Duration
+	daily         *dailyRotation
+	closeWorkers  int
+	closeQueue    chan pendingClose
+	fileHeader    []byte
+	header        []byte // fileHeader followed by the labels line of WithLokiLabels
+	lokiLabels    []byte // JSON object
+	sharedKey     string
+	pathProvider  PathProvider
+	levelSizes    map[int8]int64
+	shared        *sharedSeq
+	localSeq      atomic.Uint64 // shared sequence number of the last reopen
+	noAppend      bool
+	signalCheck   bool
+	atomicReplace bool
+	replaceDir    string
+	clock         Clock
+	lastWrite     atomic.Int64 // unix nano
+	idle          atomic.Bool
+	fileSystem    FileSystem
+	shardIDs      sync.Pool
+
+	afterClose []func(finalPath string)
+	background sync.WaitGroup
 
 	closing chan bool
 }
@@ -30,73 +183,935 @@ type ReopenableWriteSyncer struct {
 // mode specify the file mode when open it.
 // sig specify which signals need to be monitored by reopen mechanics(default is USR1).
 func New(file string, mode os.FileMode, sig ...os.Signal) (*ReopenableWriteSyncer, error) {
+	return NewWithOptions(file, WithFileMode(mode), WithSignals(sig...))
+}
+
+// NewWithOptions create reopen-support writeSyncer for file and configure it with opts.
+func NewWithOptions(file string, opts ...Option) (*ReopenableWriteSyncer, error) {
+	return newWriteSyncer(file, nil, opts)
+}
+
+// NewFromFile create reopen-support writeSyncer writing to the already opened f, e.g. a file opened before
+// dropping privileges or passed by systemd. Reopens open f.Name() again with the usual flags,
+// f is closed like any previous file once replaced, it is left open if an error is returned.
+func NewFromFile(f *os.File, opts ...Option) (*ReopenableWriteSyncer, error) {
+	if f == nil {
+		return nil, os.ErrInvalid
+	}
+	return newWriteSyncer(f.Name(), f, opts)
+}
+
+// newWriteSyncer create the writeSyncer for file, which is opened unless f is given.
+func newWriteSyncer(file string, f *os.File, opts []Option) (*ReopenableWriteSyncer, error) {
 	ws := &ReopenableWriteSyncer{
-		filePath:  file,
-		fileMode:  mode,
-		reopenSig: make(chan os.Signal, 1),
-		closing:   make(chan bool, 1),
+		filePath:         file,
+		fileMode:         defaultFileMode,
+		openFlag:         openFlag,
+		drainTimeout:     defaultDrainTimeout,
+		quarantine:       os.Stderr,
+		traceExtractor:   traceFromContext,
+		namer:            TimestampNamer{},
+		truncationMarker: defaultTruncationMarker,
+		sigBufferSize:    1,
+		syncInterval:     asyncSyncInterval,
+		fileSystem:       osFileSystem{},
+		maxFailures:      defaultMaxConsecutiveErrors,
+		closeWorkers:     1,
+		closeStrategy:    TimeCloseStrategy(defaultCloseDelay),
+		clock:            RealClock{},
+		syncKick:         make(chan struct{}, 1),
+		closing:          make(chan bool, 1),
+	}
+	for _, opt := range opts {
+		opt(ws)
+	}
+	if ws.levelSizes != nil {
+		return nil, ErrNotApplicable
 	}
-	if err := ws.open(); err != nil {
+	ws.reopenSig = make(chan os.Signal, ws.sigBufferSize)
+	if ws.closeWorkers > 0 {
+		ws.closeQueue = make(chan pendingClose, closeQueueSize)
+	}
+	if err := ws.canonicalize(); err != nil {
 		return nil, err
 	}
-	if len(sig) == 0 {
-		sig = append(sig, syscall.SIGUSR1)
+	if ws.sharedKey != "" {
+		shared, err := openSharedSeq(sharedSeqPath(ws.sharedKey), ws.fileMode)
+		if err != nil {
+			return nil, err
+		}
+		ws.shared = shared
+		ws.localSeq.Store(shared.load())
+	}
+	if ws.nfsAware {
+		networkFS, err := isNetworkFS(ws.filePath)
+		if err != nil {
+			return nil, err
+		}
+		if networkFS {
+			ws.networkFS = true
+			if ws.fileWatcher || ws.validationInterval > 0 {
+				ws.handleError(ErrNetworkFilesystem)
+			}
+		}
+	}
+	ws.header = ws.fileHeader
+	if ws.lokiLabels != nil {
+		ws.header = append(append([]byte(nil), ws.fileHeader...), `{"labels":`...)
+		ws.header = append(append(ws.header, ws.lokiLabels...), "}\n"...)
+	}
+	if ws.encryptionKey != nil {
+		aead, err := newAEAD(ws.encryptionKey)
+		if err != nil {
+			return nil, err
+		}
+		ws.aead = aead
+	}
+	if ws.cloexec {
+		ws.openFlag |= syscall.O_CLOEXEC
+	}
+	if ws.odirect {
+		ws.openFlag |= oDirect
+	}
+	if ws.directAlignment > 0 {
+		ws.openFlag &^= os.O_APPEND // the aligned writes use explicit offsets
+	}
+	flag := ws.openFlag
+	if ws.truncateOnOpen {
+		flag |= os.O_TRUNC
+	}
+	if f != nil {
+		if ws.cloexec {
+			syscall.CloseOnExec(int(f.Fd()))
+		}
+		shards, err := ws.openShards(f.Name(), ws.openFlag)
+		if err != nil {
+			return nil, err
+		}
+		ws.use(f, shards)
+	} else if err := ws.openInitial(flag); err != nil {
+		return nil, err
+	}
+	ws.setState(StateOpen)
+	if ws.notifyAddr != "" {
+		if err := ws.dialNotify(); err != nil {
+			_ = ws.getFile().Close()
+			return nil, err
+		}
+	}
+	if ws.walPath != "" {
+		if err := ws.openWAL(); err != nil {
+			_ = ws.getFile().Close()
+			ws.closeNotify()
+			return nil, err
+		}
+		ws.goBackground("wal", ws.flushWAL)
+	}
+	if !ws.grouped {
+		if len(ws.signals) == 0 {
+			ws.signals = append(ws.signals, syscall.SIGUSR1)
+		}
+		signal.Notify(ws.reopenSig, ws.signals...)
+		if ws.signalCheck {
+			if err := ws.checkSignals(); err != nil {
+				_ = ws.Close()
+				return nil, err
+			}
+		}
+		ws.watcherRunning.Store(true)
+		ws.goBackground("watch", ws.watchWithRestart)
+	}
+	if ws.asyncSync {
+		ws.goBackground("sync", ws.syncLoop)
+	}
+	if ws.truncationInterval > 0 {
+		ws.goBackground("truncation", ws.detectTruncation)
+	}
+	if ws.validationInterval > 0 && !ws.networkFS {
+		ws.goBackground("validation", ws.validate)
+	}
+	if ws.daily != nil {
+		ws.goBackground("daily", ws.rotateDaily)
+	}
+	for i := 0; i < ws.closeWorkers; i++ {
+		ws.goBackground("close", ws.closeWorker)
+	}
+	if ws.dedup != nil {
+		ws.goBackground("dedup", ws.flushDeduplicated)
+	}
+	if ws.idleTimeout > 0 {
+		ws.lastWrite.Store(ws.clock.Now().UnixNano())
+		ws.goBackground("idle", ws.closeInactive)
+	}
+	if ws.fileWatcher && !ws.networkFS {
+		w, err := newFileWatcher(ws.activePath())
+		if err != nil {
+			_ = ws.Close()
+			return nil, err
+		}
+		ws.watcher = w
+		ws.goBackground("inotify", w.run)
+		ws.goBackground("file-watch", func() { ws.watchFile(w) })
+	}
+	if t := ws.httpTrigger; t != nil {
+		if err := t.register(ws.rotationHandler(t.token)); err != nil {
+			_ = ws.Close()
+			return nil, err
+		}
 	}
-	signal.Notify(ws.reopenSig, sig...)
-	go ws.watch()
 	return ws, nil
 }
 
 func (ws *ReopenableWriteSyncer) Write(p []byte) (n int, err error) {
-	return ws.getFile().Write(p)
+	if ws.noFile() {
+		return 0, os.ErrClosed
+	}
+	if ws.shared != nil {
+		ws.followShared()
+	}
+	if ws.dedup != nil {
+		n, err = ws.writeDeduplicated(p)
+	} else {
+		n, err = ws.write(p)
+	}
+	ws.writes.Add(1)
+	ws.logicalBytes.Add(int64(n))
+	return n, err
+}
+
+// WriteN writes the first min(n, len(p)) bytes of p.
+func (ws *ReopenableWriteSyncer) WriteN(n int, p []byte) (int, error) {
+	if n < 0 {
+		n = 0
+	}
+	if n < len(p) {
+		p = p[:n]
+	}
+	return ws.Write(p)
+}
+
+// WriteFull writes p, retrying the rest of it after a short write, until all of p is written or the write fails
+// with another error than io.ErrShortWrite, EINTR or EAGAIN.
+func (ws *ReopenableWriteSyncer) WriteFull(p []byte) error {
+	for len(p) > 0 {
+		n, err := ws.Write(p)
+		p = p[n:]
+		if err != nil && !errors.Is(err, io.ErrShortWrite) && !errors.Is(err, syscall.EINTR) && !errors.Is(err, syscall.EAGAIN) {
+			return err
+		}
+		if n == 0 && err == nil {
+			return io.ErrShortWrite
+		}
+	}
+	return nil
+}
+
+func (ws *ReopenableWriteSyncer) write(p []byte) (n int, err error) {
+	if ws.jsonValidation && !json.Valid(p) {
+		ws.writeErrors.Add(1)
+		return ws.quarantine.Write(p)
+	}
+	if ws.jsonlValidation && !json.Valid(bytes.TrimRight(p, "\n")) {
+		return ws.writeInvalidLine(p)
+	}
+	out, buf := ws.transform(p)
+	defer ws.putBuffer(buf)
+	if ws.wal != nil {
+		n, err = ws.writeWAL(out)
+	} else if ws.batcher != nil {
+		n, err = ws.batcher.write(out)
+	} else if ws.orderer != nil {
+		n, err = ws.orderer.write(out)
+	} else {
+		n, err = ws.writeFile(out)
+	}
+	if n == len(out) {
+		// the caller sees its own payload written, whatever the transformations did.
+		return len(p), err
+	}
+	if n > len(p) {
+		n = len(p)
+	}
+	return n, err
+}
+
+// invalidLine replaces a payload rejected by WithJSONLValidation in the file.
+type invalidLine struct {
+	Invalid bool   `json:"_invalid"`
+	Raw     []byte `json:"_raw"`
+	TS      string `json:"ts"`
+}
+
+// writeInvalidLine writes p to the quarantine writer and an invalidLine holding it to the file.
+func (ws *ReopenableWriteSyncer) writeInvalidLine(p []byte) (n int, err error) {
+	ws.writeErrors.Add(1)
+	if _, err := ws.quarantine.Write(p); err != nil {
+		ws.handleError(fmt.Errorf("reopen: quarantine: %w", err))
+	}
+	line, err := json.Marshal(invalidLine{Invalid: true, Raw: p, TS: ws.clock.Now().UTC().Format(time.RFC3339Nano)})
+	if err != nil {
+		return 0, err
+	}
+	if _, err := ws.write(append(line, '\n')); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// writeChunks writes p to f in chunks of at most atomicWriteSize bytes, chunks of concurrent writes of this
+// process never interleave.
+func (ws *ReopenableWriteSyncer) writeChunks(f *logFile, p []byte) (n int, err error) {
+	ws.chunkMu.Lock()
+	defer ws.chunkMu.Unlock()
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > ws.atomicWriteSize {
+			chunk = chunk[:ws.atomicWriteSize]
+		}
+		m, err := f.Write(chunk)
+		n += m
+		if err != nil {
+			return n, err
+		}
+		p = p[m:]
+	}
+	return n, nil
+}
+
+// writeFile writes p to the current file.
+func (ws *ReopenableWriteSyncer) writeFile(p []byte) (n int, err error) {
+	if ws.atomicReplace {
+		return ws.writeReplace(p)
+	}
+	f := ws.acquire()
+	if f == nil {
+		return 0, os.ErrClosed
+	}
+	physical := 0 // bytes written to the file, they differ from n when encrypting
+	if f.enc != nil {
+		n, physical, err = f.enc.write(f.File, p)
+	} else if ws.directAlignment > 0 {
+		n, err = ws.writeAligned(f, p)
+	} else if ws.atomicWriteSize > 0 && len(p) > ws.atomicWriteSize {
+		n, err = ws.writeChunks(f, p)
+	} else if len(f.shards) > 0 {
+		n, err = ws.writeShard(f, p)
+	} else {
+		n, err = f.Write(p)
+	}
+	if f.enc == nil {
+		physical = n
+	}
+	f.written.Add(int64(physical))
+	f.lines.Add(int64(bytes.Count(p[:n], []byte{'\n'})))
+	ws.physicalBytes.Add(int64(physical))
+	if ws.idleTimeout > 0 {
+		ws.lastWrite.Store(ws.clock.Now().UnixNano())
+	}
+	if ws.syncMinBytes > 0 {
+		ws.unsyncedBytes.Add(int64(physical))
+		ws.kickSync()
+	}
+	truncated := err == nil && ws.copyTruncateDetection && f.truncated()
+	f.release()
+	if err != nil {
+		ws.writeErrors.Add(1)
+	}
+	if truncated && f.reportTruncation() {
+		if err := ws.rotate(TriggerTruncation, nil); err != nil && err != os.ErrClosed {
+			ws.handleError(err)
+		}
+	}
+	return n, err
 }
 
-// wrap all the WriteSyncer methods to use getFile
+// wrap all the WriteSyncer methods to use acquire
 // example with Sync
 func (ws *ReopenableWriteSyncer) Sync() error {
-	return ws.getFile().Sync()
+	if ws.noFile() {
+		return os.ErrClosed
+	}
+	ws.syncs.Add(1)
+	if ws.asyncSync {
+		ws.pendingSync.Store(true)
+		if ws.syncMinBytes > 0 {
+			ws.kickSync()
+		}
+		return nil
+	}
+	return ws.syncFile()
 }
 
+func (ws *ReopenableWriteSyncer) syncFile() error {
+	f := ws.acquireIfOpen()
+	if f == nil {
+		if ws.idle.Load() && !ws.closed() {
+			return nil // goIdle synced the file before closing it
+		}
+		return os.ErrClosed
+	}
+	defer f.release()
+	if f.enc != nil {
+		if err := ws.flushEncrypted(f); err != nil {
+			return err
+		}
+	}
+	err := f.Sync()
+	if ws.stdio && (errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.ENOTSUP)) {
+		// pipes and terminals cannot be synced
+		return nil
+	}
+	return err
+}
+
+// Fd returns the file descriptor of the current file, or ^uintptr(0) if there is none.
+// The descriptor changes after every reopen and the file it refers to is closed shortly afterwards,
+// so callers should not hold on to it.
+func (ws *ReopenableWriteSyncer) Fd() uintptr {
+	if ws.noFile() {
+		return ^uintptr(0)
+	}
+	return ws.getFile().Fd()
+}
+
+// Reopen closes the current file once its in-flight writes finish and opens the file path again,
+// just like receiving one of the monitored signals.
+func (ws *ReopenableWriteSyncer) Reopen() error {
+	if ws.noFile() {
+		return os.ErrClosed
+	}
+	return ws.rotate(TriggerManual, nil)
+}
+
+// Relocate switches the writer to newPath: the file at newPath is opened and the current one is closed
+// once its in-flight writes finish, like a reopen. Writes go either to the old or to the new file meanwhile.
+// The signals monitored are unchanged, later reopens use newPath.
+func (ws *ReopenableWriteSyncer) Relocate(newPath string) error {
+	if ws.noFile() {
+		return os.ErrClosed
+	}
+	if ws.stdio {
+		return nil
+	}
+	ws.waitJitter()
+	ws.reloadMu.Lock()
+	defer ws.reloadMu.Unlock()
+	if ws.closed() {
+		return os.ErrClosed
+	}
+	oldPath := ws.filePath
+	ws.filePath = newPath
+	if err := ws.rotateLocked(TriggerRelocate, nil, ""); err != nil {
+		ws.filePath = oldPath
+		return err
+	}
+	return nil
+}
+
+// CurrentFilePath returns the path of the file being written, or "" if there is none.
+func (ws *ReopenableWriteSyncer) CurrentFilePath() string {
+	if ws.noFile() {
+		return ""
+	}
+	return ws.getFile().Name()
+}
+
+// Close stops all background goroutines and closes every file, including the ones waiting to drain
+// after a reopen, then runs the WithAfterClose callbacks.
 func (ws *ReopenableWriteSyncer) Close() error {
+	if ws.noFile() {
+		return os.ErrClosed
+	}
+	if ws.dedup != nil && !ws.closed() {
+		for _, s := range ws.dedup.flush() {
+			_, _ = ws.write(s)
+		}
+	}
+	if ws.batcher != nil {
+		ws.batcher.flush()
+	}
+	if ws.orderer != nil {
+		ws.orderer.flush()
+	}
+	ws.reloadMu.Lock()
+	if ws.closed() {
+		ws.reloadMu.Unlock()
+		return os.ErrClosed
+	}
+	signal.Stop(ws.reopenSig)
 	close(ws.closing)
-	return ws.getFile().Close()
+	ws.setState(StateDraining)
+	ws.reloadMu.Unlock()
+
+	if ws.wal != nil {
+		ws.walMu.Lock() // wait for the WAL writes in progress, see writeWAL
+		ws.walCond.Broadcast()
+		ws.walMu.Unlock()
+		ws.closeWAL()
+	}
+	f := ws.getFile()
+	idle := ws.idle.Load()
+	f.retire(ws.drainTimeout)
+	var syncErr error
+	if ws.asyncSync && !idle {
+		ws.pendingSync.Store(false)
+		syncErr = f.Sync()
+	}
+	finalPath := ws.finishActive(f)
+	var err error
+	if !idle {
+		err = ws.closeFile(f)
+	}
+	ws.background.Wait()
+	ws.closeNotify()
+	ws.setState(StateClosed)
+	for _, fn := range ws.afterClose {
+		ws.runAfterClose(fn, finalPath)
+	}
+	if err != nil {
+		return err
+	}
+	return syncErr
+}
+
+func (ws *ReopenableWriteSyncer) runAfterClose(fn func(finalPath string), finalPath string) {
+	defer func() {
+		if r := recover(); r != nil {
+			ws.handleError(fmt.Errorf("reopen: after close callback panicked: %v", r))
+		}
+	}()
+	fn(finalPath)
+}
+
+// CloseErr returns the last error observed while closing a file, either by Close or after a reopen.
+func (ws *ReopenableWriteSyncer) CloseErr() error {
+	if ws.noFile() {
+		return nil
+	}
+	ws.closeErrMu.Lock()
+	defer ws.closeErrMu.Unlock()
+	return ws.closeErr
+}
+
+// closeFile closes f and records the error for CloseErr and the error handler.
+func (ws *ReopenableWriteSyncer) closeFile(f *logFile) error {
+	if f.enc != nil {
+		if err := ws.flushEncrypted(f); err != nil {
+			ws.handleError(err)
+		}
+	}
+	if ws.directAlignment > 0 {
+		ws.truncatePadding(f)
+	}
+	for _, shard := range f.shards {
+		_ = shard.Close()
+	}
+	err := f.Close()
+	if err != nil {
+		ws.closeErrMu.Lock()
+		ws.closeErr = err
+		ws.closeErrMu.Unlock()
+		ws.handleError(err)
+	}
+	for _, fn := range f.markClosed() {
+		fn()
+	}
+	return err
 }
 
-func (ws *ReopenableWriteSyncer) getFile() *os.File {
-	return ws.cur.Load().(*os.File)
+// goBackground runs fn in a goroutine which Close waits for, labeled reopen=name and path=<file path>
+// so the goroutine profiles tell which writer it serves.
+// It must not be called after Close, which is guaranteed by holding reloadMu while the writer is open
+// or by calling it from another goroutine started by goBackground.
+func (ws *ReopenableWriteSyncer) goBackground(name string, fn func()) {
+	labels := pprof.Labels("reopen", name, "path", ws.filePath)
+	ws.background.Add(1)
+	go func() {
+		defer ws.background.Done()
+		pprof.Do(context.Background(), labels, func(context.Context) { fn() })
+	}()
 }
 
-func (ws *ReopenableWriteSyncer) watch() {
+// getFile returns the current file, or nil before the writer has one, e.g. for a nil or zero ReopenableWriteSyncer.
+func (ws *ReopenableWriteSyncer) getFile() *logFile {
+	if ws == nil {
+		return nil
+	}
+	f, _ := ws.cur.Load().(*logFile)
+	return f
+}
+
+// noFile reports whether ws is nil or has no file, the exported methods then return os.ErrClosed
+// instead of panicking.
+func (ws *ReopenableWriteSyncer) noFile() bool {
+	return ws == nil || ws.getFile() == nil
+}
+
+// acquire returns the current file with an in-flight operation registered on it,
+// or nil if the writer has been closed or has no file.
+//
+// The write path is an atomic load of the current file plus a read lock on that file only,
+// writers never contend with each other and only wait for the reload goroutine while it retires the old file.
+// A writer goroutine fed by a channel would add a copy and a context switch to every write, and a RWMutex
+// over the whole writer would make writers wait for the new file to be opened, so neither is used.
+// BenchmarkWriteStrategy measured, writing 65-byte lines to /dev/null from 1 to 64 goroutines on a single vCPU
+// Xeon with go1.27: atomic 251-274 ns/op, rwmutex 270-347 ns/op and channel 351-524 ns/op, the gap growing with
+// the goroutines. A single CPU shows the cost per write rather than the contention between cores,
+// run it on the target machine before changing this.
+func (ws *ReopenableWriteSyncer) acquire() *logFile {
+	if ws == nil {
+		return nil
+	}
+	for {
+		f := ws.getFile()
+		if f == nil {
+			return nil
+		}
+		if f.acquire() {
+			return f
+		}
+		if ws.getFile() == f {
+			if ws.idle.Load() && ws.wakeUp() {
+				continue
+			}
+			return nil
+		}
+	}
+}
+
+// acquireIfOpen is acquire for Sync and the pollers, which must not open the file of an idle writer again:
+// it returns nil while the writer is idle.
+func (ws *ReopenableWriteSyncer) acquireIfOpen() *logFile {
+	if ws == nil {
+		return nil
+	}
+	for {
+		f := ws.getFile()
+		if f == nil {
+			return nil
+		}
+		if f.acquire() {
+			return f
+		}
+		if ws.getFile() == f {
+			return nil
+		}
+	}
+}
+
+// watch reopens the file on every signal. A failed reopen is reported to the error handler and the next signal
+// is awaited after a short delay, watch returns the error once WithMaxConsecutiveErrors reopens failed in a row.
+func (ws *ReopenableWriteSyncer) watch() error {
+	failures := 0
 	for {
 		select {
 		case <-ws.closing:
+			return nil
+		case sig := <-ws.reopenSig:
+			err := ws.rotate(TriggerSignal, sig)
+			if err == nil || err == os.ErrClosed {
+				failures = 0
+				continue
+			}
+			ws.handleError(err)
+			if failures++; failures >= ws.maxFailures {
+				return err
+			}
+			select {
+			case <-ws.closing:
+				return nil
+			case <-time.After(watcherRestartDelay):
+			}
+		}
+	}
+}
+
+// watchWithRestart runs watch and restarts it with an exponential backoff once it gave up,
+// up to WithMaxRestarts times. ErrWatcherDead is reported to the error handler once it gives up.
+func (ws *ReopenableWriteSyncer) watchWithRestart() {
+	defer ws.watcherRunning.Store(false)
+	delay := watcherRestartDelay
+	for {
+		err := ws.watch()
+		if err == nil {
+			return
+		}
+		if ws.watcherRestarts.Load() >= int64(ws.maxRestarts) {
+			ws.handleError(fmt.Errorf("%w: %v", ErrWatcherDead, err))
 			return
-		case <-ws.reopenSig:
-			if err := ws.reload(); err != nil {
-				return
+		}
+		select {
+		case <-ws.closing:
+			return
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > maxWatcherRestartDelay {
+			delay = maxWatcherRestartDelay
+		}
+		ws.reloadMu.Lock()
+		if ws.closed() {
+			ws.reloadMu.Unlock()
+			return
+		}
+		signal.Stop(ws.reopenSig)
+		signal.Notify(ws.reopenSig, ws.signals...)
+		ws.reloadMu.Unlock()
+		ws.watcherRestarts.Add(1)
+	}
+}
+
+// rotate reopens the file because of trigger, sig is the signal received for TriggerSignal.
+// It returns os.ErrClosed once the writer is closed and does nothing when a signal arrives
+// within the rotation guard interval.
+func (ws *ReopenableWriteSyncer) rotate(trigger RotationTrigger, sig os.Signal) error {
+	if ws.stdio {
+		return nil
+	}
+	if trigger == TriggerSignal || trigger == TriggerManual {
+		ws.waitAlone()
+	}
+	ws.waitJitter()
+	ws.reloadMu.Lock()
+	defer ws.reloadMu.Unlock()
+	if ws.closed() {
+		return os.ErrClosed
+	}
+	return ws.rotateLocked(trigger, sig, "")
+}
+
+// rotateLocked is rotate for callers holding reloadMu on an open writer,
+// backupPath is where the previous file has been renamed to, if known.
+func (ws *ReopenableWriteSyncer) rotateLocked(trigger RotationTrigger, sig os.Signal, backupPath string) error {
+	now := ws.clock.Now()
+	if trigger == TriggerSignal && ws.guardInterval > 0 && now.Sub(time.Unix(0, ws.lastRotation.Load())) < ws.guardInterval {
+		return nil
+	}
+	oldPath := ws.filePath
+	if ws.pathProvider != nil && trigger != TriggerRelocate {
+		seq := int(ws.rotations.Load()) + 1
+		if pp, ok := ws.pathProvider.(clockPathProvider); ok {
+			ws.filePath = pp.nextPathAt(oldPath, seq, now)
+		} else {
+			ws.filePath = ws.pathProvider.NextPath(oldPath, seq)
+		}
+	}
+	old := ws.getFile()
+	ws.setState(StateRotating)
+	if err := ws.reload(); err == errReopenSuperseded {
+		return nil
+	} else if err != nil {
+		ws.filePath = oldPath
+		if err != os.ErrClosed {
+			ws.setState(StateError)
+		}
+		return err
+	}
+	ws.setState(StateOpen)
+	if ws.watcher != nil && ws.activePath() != old.Name() {
+		if err := ws.watcher.watch(ws.activePath()); err != nil {
+			ws.handleError(fmt.Errorf("reopen: file watcher: %w", err))
+		}
+	}
+	if ws.shared != nil && trigger != TriggerCoordination {
+		ws.localSeq.Store(ws.shared.increment())
+	}
+	ws.lastRotation.Store(now.UnixNano())
+	ws.rotations.Add(1)
+	ev := RotationEvent{Path: ws.filePath, BackupPath: backupPath, Time: now, Trigger: trigger, Signal: sig}
+	ws.emit(ev)
+	for _, fn := range ws.postRotate {
+		fn(ev)
+	}
+	// the backup is handed over once no write can reach it, i.e. once the close strategy closed it
+	old.whenClosed(func() {
+		ws.runPostRotateCommand(ev)
+		ws.runArchiver(ev)
+	})
+	ws.notify()
+	return nil
+}
+
+func (ws *ReopenableWriteSyncer) closed() bool {
+	select {
+	case <-ws.closing:
+		return true
+	default:
+		return false
+	}
+}
+
+// syncLoop performs the fsync requested by Sync when WithAsyncSync is enabled.
+func (ws *ReopenableWriteSyncer) syncLoop() {
+	ticker := time.NewTicker(ws.syncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ws.closing:
+			return
+		case <-ticker.C:
+		case <-ws.syncKick:
+		}
+		if ws.pendingSync.Swap(false) {
+			ws.unsyncedBytes.Store(0)
+			_ = ws.syncFile()
+		}
+	}
+}
+
+// kickSync wakes syncLoop up if a sync is pending and WithSyncCoalescing's minBytes have been written since the last one.
+func (ws *ReopenableWriteSyncer) kickSync() {
+	if ws.pendingSync.Load() && ws.unsyncedBytes.Load() >= ws.syncMinBytes {
+		select {
+		case ws.syncKick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+const openFlag = os.O_WRONLY | os.O_APPEND | os.O_CREATE
+
+// detectTruncation reopens the file when it becomes smaller than what has been written to it,
+// which is what logrotate copytruncate does without sending any signal.
+func (ws *ReopenableWriteSyncer) detectTruncation() {
+	ticker := time.NewTicker(ws.truncationInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ws.closing:
+			return
+		case <-ticker.C:
+			f := ws.acquireIfOpen()
+			if f == nil {
+				continue // idle, closed files are not truncated
+			}
+			truncated := f.truncated()
+			f.release()
+			if truncated && f.reportTruncation() {
+				if err := ws.rotate(TriggerTruncation, nil); err != nil && err != os.ErrClosed {
+					ws.handleError(err)
+				}
 			}
 		}
 	}
 }
 
-func (ws *ReopenableWriteSyncer) open() error {
-	f, err := os.OpenFile(ws.filePath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, ws.fileMode)
+func (ws *ReopenableWriteSyncer) open(flag int) error {
+	f, err := ws.fileSystem.OpenFile(ws.activePath(), flag, ws.fileMode)
 	if err != nil {
 		return err
 	}
-	ws.cur.Store(f)
+	if ws.noAppend && flag&os.O_TRUNC == 0 {
+		if f, err = ws.ensureEmpty(f, flag); err != nil {
+			return err
+		}
+	}
+	if ws.chmod != nil {
+		if err := f.Chmod(*ws.chmod); err != nil {
+			ws.handleError(fmt.Errorf("reopen: chmod %s: %w", f.Name(), err))
+		}
+	}
+	shards, err := ws.openShards(f.Name(), flag&^os.O_TRUNC)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	ws.use(f, shards)
 	return nil
 }
 
+// use makes f the current file.
+func (ws *ReopenableWriteSyncer) use(f *os.File, shards []*os.File) {
+	lf := &logFile{File: f, opened: ws.clock.Now(), shards: shards}
+	if fi, err := f.Stat(); err == nil {
+		lf.baseSize = fi.Size()
+		lf.logical = lf.baseSize
+		if ws.aead != nil {
+			if lf.enc = ws.sharedEncryptor(fi); lf.enc == nil {
+				lf.enc = ws.newEncryptor(lf)
+			}
+		}
+		if lf.baseSize == 0 && len(ws.header) > 0 {
+			ws.writeHeader(lf)
+		}
+	}
+	ws.cur.Store(lf)
+}
+
+// errReopenSuperseded is returned by openRetrying when another reopen, or an idle close, happened while it was waiting
+// to retry, the reopen then has nothing left to do.
+var errReopenSuperseded = errors.New("reopen: reopen superseded")
+
+// openInitial opens the file when the writer is created, retrying as configured by WithOpenRetry,
+// or by WithOpenRetryPolicy if WithOpenRetry is not used.
+func (ws *ReopenableWriteSyncer) openInitial(flag int) error {
+	policy := ws.retryPolicy
+	if ws.openRetry != nil {
+		policy = *ws.openRetry
+	}
+	for attempt := 1; ; attempt++ {
+		err := ws.open(flag)
+		if err == nil || policy == nil {
+			return err
+		}
+		delay, retry := policy.NextDelay(attempt, err)
+		if !retry {
+			return err
+		}
+		ws.handleError(&RetryEvent{Attempt: attempt, Delay: delay, Err: err})
+		time.Sleep(delay)
+	}
+}
+
+// openRetrying reopens the file like open, retrying as configured by WithOpenRetryPolicy. It is called with reloadMu
+// held and releases it while waiting for the next attempt, so neither the writers waking the writer up nor Close
+// wait for the retries. It returns os.ErrClosed if the writer has been closed meanwhile and errReopenSuperseded
+// if the current file has changed.
+func (ws *ReopenableWriteSyncer) openRetrying(flag int) error {
+	cur, idle := ws.getFile(), ws.idle.Load()
+	for attempt := 1; ; attempt++ {
+		err := ws.open(flag)
+		if err == nil || ws.retryPolicy == nil {
+			return err
+		}
+		delay, retry := ws.retryPolicy.NextDelay(attempt, err)
+		if !retry {
+			return err
+		}
+		ws.handleError(&RetryEvent{Attempt: attempt, Delay: delay, Err: err})
+		ws.reloadMu.Unlock()
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ws.closing:
+			timer.Stop()
+		}
+		ws.reloadMu.Lock()
+		if ws.closed() {
+			return os.ErrClosed
+		}
+		if ws.getFile() != cur || ws.idle.Load() != idle {
+			return errReopenSuperseded
+		}
+	}
+}
+
 func (ws *ReopenableWriteSyncer) reload() error {
+	if ws.idle.Load() {
+		if err := ws.openRetrying(ws.openFlag); err != nil {
+			return err
+		}
+		ws.idle.Store(false)
+		return nil
+	}
 	oldDest := ws.getFile()
-	if err := ws.open(); err != nil {
+	ws.finishActive(oldDest)
+	if err := ws.openRetrying(ws.openFlag); err != nil {
 		return err
 	}
+	if ws.directAlignment > 0 {
+		ws.inheritAligned(oldDest, ws.getFile())
+	}
 
-	go func() {
-		time.Sleep(time.Second * 10)
-		_ = oldDest.Close()
-	}()
+	ws.closeOld(oldDest)
 	return nil
 }
@@ -0,0 +1,202 @@
+package reopen_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/owarai/reopen"
+	"github.com/owarai/reopen/reopentest"
+)
+
+// BenchmarkWriteStrategy compares the ways of swapping the file under concurrent writers,
+// see the doc comment of acquire for the results.
+func BenchmarkWriteStrategy(b *testing.B) {
+	strategies := []struct {
+		name string
+		new  func(f *os.File) (write func(p []byte), stop func())
+	}{
+		{"atomic", func(f *os.File) (func([]byte), func()) {
+			var cur atomic.Pointer[os.File]
+			cur.Store(f)
+			return func(p []byte) { _, _ = cur.Load().Write(p) }, func() {}
+		}},
+		{"rwmutex", func(f *os.File) (func([]byte), func()) {
+			var mu sync.RWMutex
+			return func(p []byte) {
+				mu.RLock()
+				_, _ = f.Write(p)
+				mu.RUnlock()
+			}, func() {}
+		}},
+		{"channel", func(f *os.File) (func([]byte), func()) {
+			ch := make(chan []byte, 1024)
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for p := range ch {
+					_, _ = f.Write(p)
+				}
+			}()
+			write := func(p []byte) { ch <- append([]byte(nil), p...) }
+			stop := func() {
+				close(ch)
+				<-done
+			}
+			return write, stop
+		}},
+	}
+	line := []byte(`{"level":"info","msg":"benchmark line of a typical size","n":42}` + "\n")
+	for _, s := range strategies {
+		for _, goroutines := range []int{1, 2, 4, 8, 16, 32, 64} {
+			b.Run(fmt.Sprintf("%s/goroutines-%d", s.name, goroutines), func(b *testing.B) {
+				f, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+				if err != nil {
+					b.Fatal(err)
+				}
+				defer f.Close()
+				write, stop := s.new(f)
+				b.SetBytes(int64(len(line)))
+				b.ResetTimer()
+				var wg sync.WaitGroup
+				for g := 0; g < goroutines; g++ {
+					n := b.N / goroutines
+					if g < b.N%goroutines {
+						n++
+					}
+					wg.Add(1)
+					go func(n int) {
+						defer wg.Done()
+						for i := 0; i < n; i++ {
+							write(line)
+						}
+					}(n)
+				}
+				wg.Wait()
+				stop()
+			})
+		}
+	}
+}
+
+// TestMethodsWithoutFile calls every method of a closed, a zero and a nil writer, none of them may panic
+// and the ones returning an error must return os.ErrClosed or io.ErrClosedPipe.
+func TestMethodsWithoutFile(t *testing.T) {
+	closed, err := reopen.NewWithOptions(filepath.Join(t.TempDir(), "app.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reopentest.MustClose(t, closed)
+	writers := map[string]*reopen.ReopenableWriteSyncer{
+		"closed": closed,
+		"zero":   new(reopen.ReopenableWriteSyncer),
+		"nil":    nil,
+	}
+	for name, ws := range writers {
+		t.Run(name, func(t *testing.T) {
+			calls := map[string]func() error{
+				"Write":       func() error { _, err := ws.Write([]byte("x\n")); return err },
+				"WriteString": func() error { _, err := ws.WriteString("x\n"); return err },
+				"WriteN":      func() error { _, err := ws.WriteN(1, []byte("x\n")); return err },
+				"WriteFull":   func() error { return ws.WriteFull([]byte("x\n")) },
+				"WriteCtx": func() error {
+					ctx, cancel := context.WithCancel(context.Background())
+					defer cancel()
+					_, err := ws.WriteCtx(ctx, []byte("x\n"))
+					return err
+				},
+				"Sync":     ws.Sync,
+				"Reopen":   ws.Reopen,
+				"Rotate":   ws.Rotate,
+				"Relocate": func() error { return ws.Relocate(filepath.Join(t.TempDir(), "other.log")) },
+				"Close":    ws.Close,
+			}
+			for method, call := range calls {
+				if err := call(); !errors.Is(err, os.ErrClosed) && !errors.Is(err, io.ErrClosedPipe) {
+					t.Errorf("%s returned %v, want os.ErrClosed or io.ErrClosedPipe", method, err)
+				}
+			}
+			_ = ws.CurrentFilePath()
+			_ = ws.Fd()
+			_ = ws.FileAge()
+			_ = ws.CurrentFileBytesWritten()
+			_ = ws.CurrentFileLineCount()
+			_ = ws.PendingBytes()
+			_ = ws.Stats()
+			_ = ws.State()
+			_ = ws.CloseErr()
+			if _, err := json.Marshal(ws.DebugDump()); err != nil {
+				t.Errorf("DebugDump: %v", err)
+			}
+			ws.OpenMetricsHandler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/metrics", nil))
+		})
+	}
+}
+
+func TestCloseIdleFinishesActive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	ws, err := reopen.NewWithOptions(path, reopen.WithActiveSuffix(".writing"),
+		reopen.WithInactivityClose(20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ws.Write([]byte("line\n")); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); ws.State() != reopen.StateIdle; time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("the writer did not go idle")
+		}
+	}
+	if err := ws.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + ".writing"); !os.IsNotExist(err) {
+		t.Errorf("the active file is still there: %v", err)
+	}
+	if b, err := os.ReadFile(path); err != nil || string(b) != "line\n" {
+		t.Errorf("final file holds %q, %v", b, err)
+	}
+}
+
+func TestIdleStaysIdle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	ws, err := reopen.NewWithOptions(path, reopen.WithInactivityClose(20*time.Millisecond),
+		reopen.WithTruncationDetector(5*time.Millisecond), reopen.WithFileWatcher())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	if _, err := ws.Write([]byte("line\n")); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); ws.State() != reopen.StateIdle; time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("the writer did not go idle")
+		}
+	}
+	if err := ws.Sync(); err != nil {
+		t.Errorf("Sync while idle: %v", err)
+	}
+	ws.OpenMetricsHandler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/metrics", nil))
+	time.Sleep(50 * time.Millisecond) // several ticks of the truncation detector
+	if s := ws.State(); s != reopen.StateIdle {
+		t.Errorf("state is %v after Sync and the pollers, want idle", s)
+	}
+
+	if _, err := ws.Write([]byte("again\n")); err != nil {
+		t.Fatal(err)
+	}
+	if s := ws.State(); s != reopen.StateOpen {
+		t.Errorf("state is %v after a write, want open", s)
+	}
+}